@@ -0,0 +1,79 @@
+// Preconditioned conjugate gradient solver for sparse symmetric positive
+// definite linear systems.
+package vo2percolation
+
+import (
+	"fmt"
+	"math"
+)
+
+const CGConvergenceError = "conjugate gradient failed to converge after %d iterations"
+
+// Function type for a linear operator: set y = A x.
+type LinearOperator func(x, y []float64)
+
+// Solve A x = b for x, where A is symmetric positive definite.  On entry x
+// holds the initial guess.  diag holds the diagonal of A for Jacobi
+// preconditioning; if it is nil no preconditioning is done.  Iteration stops
+// when |b - A x| <= tol * |b|.  Return the number of iterations taken.
+func ConjugateGradient(A LinearOperator, diag, b, x []float64, tol float64, maxIter int) (int, error) {
+	n := len(b)
+	r, z, p, Ap := make([]float64, n), make([]float64, n), make([]float64, n), make([]float64, n)
+	precondition := func(r, z []float64) {
+		for i := range r {
+			if diag != nil {
+				z[i] = r[i] / diag[i]
+			} else {
+				z[i] = r[i]
+			}
+		}
+	}
+	// r = b - A x
+	A(x, Ap)
+	for i := range r {
+		r[i] = b[i] - Ap[i]
+	}
+	bNorm := norm(b)
+	if bNorm == 0 {
+		bNorm = 1
+	}
+	if norm(r) <= tol*bNorm {
+		return 0, nil
+	}
+	precondition(r, z)
+	copy(p, z)
+	rz := dot(r, z)
+	for iter := 1; iter <= maxIter; iter++ {
+		A(p, Ap)
+		alpha := rz / dot(p, Ap)
+		for i := range x {
+			x[i] += alpha * p[i]
+			r[i] -= alpha * Ap[i]
+		}
+		if norm(r) <= tol*bNorm {
+			return iter, nil
+		}
+		precondition(r, z)
+		rzNext := dot(r, z)
+		beta := rzNext / rz
+		rz = rzNext
+		for i := range p {
+			p[i] = z[i] + beta*p[i]
+		}
+	}
+	return maxIter, fmt.Errorf(CGConvergenceError, maxIter)
+}
+
+// Inner product of x and y.
+func dot(x, y []float64) float64 {
+	sum := 0.0
+	for i := range x {
+		sum += x[i] * y[i]
+	}
+	return sum
+}
+
+// Euclidean norm of x.
+func norm(x []float64) float64 {
+	return math.Sqrt(dot(x, x))
+}
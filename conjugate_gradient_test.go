@@ -0,0 +1,28 @@
+package vo2percolation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConjugateGradient3x3(t *testing.T) {
+	// A = [[4, 1, 0], [1, 3, 1], [0, 1, 2]]
+	A := func(x, y []float64) {
+		y[0] = 4*x[0] + x[1]
+		y[1] = x[0] + 3*x[1] + x[2]
+		y[2] = x[1] + 2*x[2]
+	}
+	expected := []float64{1.0, -2.0, 3.0}
+	b := make([]float64, 3)
+	A(expected, b)
+	x := make([]float64, 3)
+	diag := []float64{4, 3, 2}
+	if _, err := ConjugateGradient(A, diag, b, x, 1e-12, 100); err != nil {
+		t.Fatal(err)
+	}
+	for i := range x {
+		if math.Abs(x[i]-expected[i]) > 1e-10 {
+			t.Fatalf("incorrect solution from ConjugateGradient")
+		}
+	}
+}
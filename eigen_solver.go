@@ -0,0 +1,204 @@
+// Reusable GSL eigensolver for real symmetric matrices.  Allocating the GSL
+// workspace, matrix and vectors dominates the cost of diagonalizing the small
+// Hamiltonians of a survey, so released workspaces are kept (up to a limit)
+// and handed out again to later calls with the same matrix size.
+package vo2percolation
+
+/*
+#cgo LDFLAGS: -lgsl -lgslcblas
+#include <gsl/gsl_math.h>
+#include <gsl/gsl_vector.h>
+#include <gsl/gsl_matrix.h>
+#include <gsl/gsl_eigen.h>
+*/
+import "C"
+
+import "sync"
+
+// Maximum number of unused workspaces an EigenSolver keeps.  Sizes vary from
+// grid to grid, so only recently used sizes are worth keeping.
+const maxFreeWorkspaces = 16
+
+// Real symmetric matrix which can be diagonalized by an EigenSolver.
+// Implemented by SymmetricMatrix and SparseSymmetricMatrix.
+type RealSymmetric interface {
+	Length() int
+	// Return true for each row with a nonzero element.
+	nonEmptyRows() []bool
+	// Write the elements of the matrix into the zeroed GSL matrix, moving the
+	// element (i, j) to (newIndex[i], newIndex[j]).
+	setReduced(matrix *C.gsl_matrix, newIndex []int)
+}
+
+// Safe for concurrent use; each call holds its own workspace.
+type EigenSolver struct {
+	lock sync.Mutex
+	// unused workspaces, least recently released first
+	free []*eigenWorkspace
+}
+
+// GSL storage for diagonalizing one matrix size.
+type eigenWorkspace struct {
+	size    int
+	vectors bool // true if allocated for eigenvectors
+	matrix  *C.gsl_matrix
+	values  *C.gsl_vector
+	evecs   *C.gsl_matrix
+	symm    *C.gsl_eigen_symm_workspace
+	symmv   *C.gsl_eigen_symmv_workspace
+}
+
+// Solver used by the Eigensystem and Eigenvalues methods of the matrix types.
+var defaultEigenSolver = NewEigenSolver()
+
+// Return a new EigenSolver with no cached workspaces.
+func NewEigenSolver() *EigenSolver {
+	return new(EigenSolver)
+}
+
+// Return the eigenvalues of m, ignoring its empty rows.  The eigenvalues are
+// not sorted.
+func (solver *EigenSolver) Eigenvalues(m RealSymmetric) ([]float64, error) {
+	newIndex, size := reducedIndex(m)
+	w, err := solver.acquire(size, false)
+	if err != nil {
+		return nil, err
+	}
+	defer solver.release(w)
+	C.gsl_matrix_set_zero(w.matrix)
+	m.setReduced(w.matrix, newIndex)
+	err = withGSLErrors(func() C.int {
+		return C.gsl_eigen_symm(w.matrix, w.values, w.symm)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vectorToSlice(w.values), nil
+}
+
+// Return the eigenvalues of m and the eigenvectors, padded to the full size
+// of m: the k'th eigenvalue belongs to the k'th nonzero vector.
+func (solver *EigenSolver) Eigensystem(m RealSymmetric) ([]float64, [][]float64, error) {
+	newIndex, size := reducedIndex(m)
+	w, err := solver.acquire(size, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer solver.release(w)
+	C.gsl_matrix_set_zero(w.matrix)
+	m.setReduced(w.matrix, newIndex)
+	err = withGSLErrors(func() C.int {
+		return C.gsl_eigen_symmv(w.matrix, w.values, w.evecs, w.symmv)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	// pad the eigenvectors back to full size
+	convert := make([]int, 0, size)
+	for iOld, iNew := range newIndex {
+		if iNew >= 0 {
+			convert = append(convert, iOld)
+		}
+	}
+	retEigenvectors := make([][]float64, m.Length())
+	for i := range retEigenvectors {
+		retEigenvectors[i] = make([]float64, m.Length())
+	}
+	for k := 0; k < size; k++ {
+		for j := 0; j < size; j++ {
+			val := C.gsl_matrix_get(w.evecs, C.size_t(j), C.size_t(k))
+			retEigenvectors[convert[k]][convert[j]] = float64(val)
+		}
+	}
+	return vectorToSlice(w.values), retEigenvectors, nil
+}
+
+// Release all cached workspaces.
+func (solver *EigenSolver) Free() {
+	solver.lock.Lock()
+	defer solver.lock.Unlock()
+	for _, w := range solver.free {
+		w.destroy()
+	}
+	solver.free = nil
+}
+
+// Return the index of each row of m after removing empty rows (-1 for empty
+// rows), and the number of non-empty rows.
+func reducedIndex(m RealSymmetric) ([]int, int) {
+	nonEmpty := m.nonEmptyRows()
+	newIndex, size := make([]int, len(nonEmpty)), 0
+	for i, val := range nonEmpty {
+		if val {
+			newIndex[i] = size
+			size++
+		} else {
+			newIndex[i] = -1
+		}
+	}
+	return newIndex, size
+}
+
+// Return a cached workspace of the given size, or allocate one.  Returns an
+// error if GSL can't allocate the workspace (e.g. for size 0).
+func (solver *EigenSolver) acquire(size int, vectors bool) (*eigenWorkspace, error) {
+	solver.lock.Lock()
+	for i, w := range solver.free {
+		if w.size == size && w.vectors == vectors {
+			solver.free = append(solver.free[:i], solver.free[i+1:]...)
+			solver.lock.Unlock()
+			return w, nil
+		}
+	}
+	solver.lock.Unlock()
+	n := C.size_t(size)
+	w := &eigenWorkspace{size: size, vectors: vectors}
+	err := withGSLErrors(func() C.int {
+		w.matrix = C.gsl_matrix_alloc(n, n)
+		w.values = C.gsl_vector_alloc(n)
+		if vectors {
+			w.evecs = C.gsl_matrix_alloc(n, n)
+			w.symmv = C.gsl_eigen_symmv_alloc(n)
+		} else {
+			w.symm = C.gsl_eigen_symm_alloc(n)
+		}
+		return 0
+	})
+	if err != nil {
+		w.destroy()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Return w to the cache, dropping the least recently released workspace if
+// the cache is full.
+func (solver *EigenSolver) release(w *eigenWorkspace) {
+	solver.lock.Lock()
+	defer solver.lock.Unlock()
+	solver.free = append(solver.free, w)
+	if len(solver.free) > maxFreeWorkspaces {
+		solver.free[0].destroy()
+		solver.free = solver.free[1:]
+	}
+}
+
+// Free the GSL storage held by w.  Fields which were never allocated are
+// nil and skipped.
+func (w *eigenWorkspace) destroy() {
+	if w.matrix != nil {
+		C.gsl_matrix_free(w.matrix)
+	}
+	if w.values != nil {
+		C.gsl_vector_free(w.values)
+	}
+	if w.evecs != nil {
+		C.gsl_matrix_free(w.evecs)
+	}
+	if w.symmv != nil {
+		C.gsl_eigen_symmv_free(w.symmv)
+	}
+	if w.symm != nil {
+		C.gsl_eigen_symm_free(w.symm)
+	}
+}
@@ -0,0 +1,77 @@
+package vo2percolation
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// The values-only path agrees with Eigensystem, including on repeated calls
+// which reuse cached workspaces.
+func TestEigenvaluesMatchEigensystem(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	grid, err := RandomConstrainedGrid(NewRandom(1), 6, 6, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	solver := NewEigenSolver()
+	defer solver.Free()
+	for _, H := range e.ElectronHamiltonian(grid) {
+		expected, _, err := solver.Eigensystem(H)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Float64s(expected)
+		for call := 0; call < 3; call++ {
+			evals, err := solver.Eigenvalues(H)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Float64s(evals)
+			if len(evals) != len(expected) {
+				t.Fatalf("got %d eigenvalues; expected %d", len(evals), len(expected))
+			}
+			for k := range evals {
+				if math.Abs(evals[k]-expected[k]) > 1e-10 {
+					t.Fatalf("eigenvalue %v differs from %v", evals[k], expected[k])
+				}
+			}
+		}
+	}
+}
+
+func TestEigenSolverConcurrent(t *testing.T) {
+	sym := NewSymmetricMatrix(3)
+	sym.Set(0, 0, 2.0)
+	sym.Set(0, 1, 1.0)
+	sym.Set(1, 1, 2.0)
+	sym.Set(2, 2, 5.0)
+	solver := NewEigenSolver()
+	defer solver.Free()
+	var wg sync.WaitGroup
+	errs := make(chan []float64, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for call := 0; call < 50; call++ {
+				evals, err := solver.Eigenvalues(sym)
+				sort.Float64s(evals)
+				if err != nil || math.Abs(evals[0]-1.0) > 1e-12 || math.Abs(evals[1]-3.0) > 1e-12 || math.Abs(evals[2]-5.0) > 1e-12 {
+					errs <- evals
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for evals := range errs {
+		t.Fatalf("unexpected eigenvalues %v", evals)
+	}
+}
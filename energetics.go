@@ -8,15 +8,41 @@ import (
 )
 
 type Energetics struct {
-	env Environment
+	env   Environment
+	model *TightBindingModel
 }
 
+// Create Energetics using the two-orbital electron model given by the fields
+// of env.
 func NewEnergetics(env Environment) *Energetics {
 	e := new(Energetics)
 	e.env = env
+	e.model = ModelFromEnvironment(env)
 	return e
 }
 
+// Create Energetics using the given electron model in place of the orbital
+// fields of env.
+func NewEnergeticsWithModel(env Environment, model *TightBindingModel) (*Energetics, error) {
+	if err := model.validate(); err != nil {
+		return nil, err
+	}
+	if err := model.validateWith(env); err != nil {
+		return nil, err
+	}
+	e := new(Energetics)
+	e.env = env
+	e.model = model
+	return e, nil
+}
+
+// Return a copy of e with inverse temperature beta.
+func (e *Energetics) WithBeta(beta float64) *Energetics {
+	copy := *e
+	copy.env.Beta = beta
+	return &copy
+}
+
 // Environment access functions
 func (e *Energetics) Beta() float64 {
 	return e.env.Beta
@@ -63,51 +89,72 @@ func (e *Energetics) SiteFlipEnergy(g *Grid, p Point) float64 {
 	return energyChange
 }
 
-// Hamiltonian for the electrons on g. Assumes two orbitals, where electrons
-// in one orbital move only in the dimer and diagonal directions. Electrons
-// on the other orbital move in both directions. Neither orbital allows the
+// Energy change corresponding to exchanging the states of the sites p and q on
+// g (a Kawasaki move).  The number of active sites is unchanged, so only the
+// change in the dimer count contributes; p and q may be neighbors.
+func (e *Energetics) SwapEnergy(g *Grid, p, q Point) float64 {
+	if g.Get(p) == g.Get(q) {
+		return 0.0
+	}
+	energyChange := e.SiteFlipEnergy(g, p)
+	g.Toggle(p)
+	energyChange += e.SiteFlipEnergy(g, q)
+	g.Toggle(p)
+	return energyChange
+}
+
+// Hamiltonian for the electrons on g, with one matrix for each decoupled
+// block of orbitals in the tight-binding model (see TightBindingModel).  The
+// default model from NewEnergetics has two orbitals, where electrons in one
+// orbital move only in the dimer direction. Electrons on the other orbital
+// move in both the dimer and diagonal directions. Neither orbital allows the
 // electrons to move in the direction perpindicular to the dimer direction.
 func (e *Energetics) ElectronHamiltonian(g *Grid) []*SymmetricMatrix {
-	alpha := NewSymmetricMatrix(g.Lx() * g.Ly())
-	beta := NewSymmetricMatrix(g.Lx() * g.Ly())
-	activePoints := g.ActiveSites().Elements()
-	convert := g.ConvertTo1D()
-	for _, p := range activePoints {
-		id := convert(p)
-		// on-site energy
-		alpha.Add(id, id, e.env.Epsilon_alpha)
-		beta.Add(id, id, e.env.Epsilon_beta)
-		// dimer-direction hopping
-		nsDim := g.DimerNeighbors(p)
-		for _, n := range nsDim {
-			if g.Get(n) {
-				nId := convert(n)
-				// factors of 1/2 due to double counting
-				alpha.Add(id, nId, -e.env.T_alpha/2)
-				beta.Add(id, nId, -e.env.T_beta_dimer/2)
-			}
-		}
-		// diagonal-direction hopping
-		nsDiag := g.DiagNeighbors(p)
-		for _, n := range nsDiag {
-			if g.Get(n) {
-				nId := convert(n)
-				beta.Add(id, nId, -e.env.T_beta_diag/2)
-			}
-		}
+	return e.model.Hamiltonian(&e.env, g)
+}
+
+// Electron Hamiltonian for g in compressed sparse row form, with the same
+// blocks as ElectronHamiltonian.
+func (e *Energetics) ElectronHamiltonianSparse(g *Grid) []*SparseSymmetricMatrix {
+	return e.model.HamiltonianSparse(&e.env, g)
+}
+
+// Return the function which gives the neighbors electrons can hop to
+// directly.  This includes hopping through a dimerized site when
+// Renormalize_dimers is set.
+func (e *Energetics) ElectronNeighbors(g *Grid) func(Point) []Point {
+	if e.env.Renormalize_dimers {
+		return g.RenormalizedNeighbors
 	}
-	return []*SymmetricMatrix{alpha, beta}
+	return g.Neighbors
+}
+
+// Return the clusters of active sites which are connected for electrons.
+func (e *Energetics) ElectronClusters(g *Grid) []*PointSet {
+	return g.AllClustersWith(e.ElectronNeighbors(g))
+}
+
+// Electron Hamiltonian for g in a perpendicular magnetic field B.  Hoppings
+// are the same as in ElectronHamiltonian, but each picks up a Peierls phase
+// (see TightBindingModel.HamiltonianPeierls).
+func (e *Energetics) ElectronHamiltonianPeierls(g *Grid) []*HermitianMatrix {
+	return e.model.HamiltonianPeierls(&e.env, g)
 }
 
 // Return a sorted list of the electronic energy levels. Each level has a
-// degeneracy of two due to the Hamiltonian's spin invariance.
-func (e *Energetics) ElectronEnergies(g *Grid) []float64 {
-	H_el := e.ElectronHamiltonian(g)
-	alpha_evals, _ := H_el[0].Eigensystem()
-	beta_evals, _ := H_el[1].Eigensystem()
-	energies := append(alpha_evals, beta_evals...)
+// degeneracy of two due to the Hamiltonian's spin invariance.  Returns an
+// error if the levels can't be found (e.g. when g has no active sites).
+func (e *Energetics) ElectronEnergies(g *Grid) ([]float64, error) {
+	energies := []float64{}
+	for _, H := range e.ElectronHamiltonian(g) {
+		evals, err := H.Eigenvalues()
+		if err != nil {
+			return nil, err
+		}
+		energies = append(energies, evals...)
+	}
 	sort.Float64s(energies)
-	return energies
+	return energies, nil
 }
 
 // Determine the Fermi energy by filling the lowest available states with
@@ -116,7 +163,10 @@ func (e *Energetics) FermiEnergy(g *Grid, particleCount int) (float64, error) {
 	if particleCount <= 0 {
 		return 0.0, fmt.Errorf("Fermi energy not defined for given number of particles")
 	}
-	energies := e.ElectronEnergies(g)
+	energies, err := e.ElectronEnergies(g)
+	if err != nil {
+		return 0.0, err
+	}
 	// numOccupied is the number of occupied energy levels
 	var numOccupied int
 	if particleCount%2 == 0 {
@@ -124,6 +174,9 @@ func (e *Energetics) FermiEnergy(g *Grid, particleCount int) (float64, error) {
 	} else {
 		numOccupied = (particleCount + 1) / 2
 	}
+	if numOccupied > len(energies) {
+		return 0.0, fmt.Errorf("too many particles for the available energy levels")
+	}
 	return energies[numOccupied-1], nil
 }
 
@@ -133,29 +186,90 @@ func FermiDist(energy float64) float64 {
 }
 
 // Return the electron number corresponding to the chemical potential mu.
+// Levels are occupied according to FermiDist(energy - mu), independent of
+// Beta.
 func (e *Energetics) NumElectrons(energies []float64, mu float64) float64 {
+	return levelOccupation(energies, 2.0, 1.0, mu) // 2 for spin degeneracy
+}
+
+// Return the number of electrons in the given levels at chemical potential
+// mu and inverse temperature beta, where each level holds up to degeneracy
+// electrons.
+func levelOccupation(energies []float64, degeneracy, beta, mu float64) float64 {
 	sum := 0.0
 	for _, energy := range energies {
 		// might want to make this a Kahan summation
-		sum += 2.0 * FermiDist(energy-mu) // 2 for spin degeneracy
+		sum += degeneracy * fermiOccupation(beta, energy-mu)
 	}
 	return sum
 }
 
+// Return FermiDist(beta * energy), which is 1/2 at energy = 0 for any beta
+// (including beta = +Inf, where the product is undefined).
+func fermiOccupation(beta, energy float64) float64 {
+	if energy == 0 {
+		return 0.5
+	}
+	return FermiDist(beta * energy)
+}
+
 // Returns the error in the number of particles calculated from mu.
-func (e *Energetics) NumElectronsError(g *Grid, particleCount int, mu float64) float64 {
-	energies := e.ElectronEnergies(g)
-	return float64(particleCount) - e.NumElectrons(energies, mu)
+func (e *Energetics) NumElectronsError(g *Grid, particleCount int, mu float64) (float64, error) {
+	energies, err := e.ElectronEnergies(g)
+	if err != nil {
+		return 0.0, err
+	}
+	return float64(particleCount) - e.NumElectrons(energies, mu), nil
 }
 
 // Find the value of mu appropriate for the given number of particles.
 func (e *Energetics) FindMu(g *Grid, particleCount int) (float64, error) {
+	energies, err := e.ElectronEnergies(g)
+	if err != nil {
+		return 0.0, err
+	}
+	return e.FindMuForLevels(energies, 2.0, particleCount)
+}
+
+// Find the value of mu which puts particleCount electrons into the given
+// levels, where each level holds up to degeneracy electrons.  Levels are
+// occupied as in NumElectrons.
+func (e *Energetics) FindMuForLevels(energies []float64, degeneracy float64, particleCount int) (float64, error) {
+	return findMuForLevels(energies, degeneracy, 1.0, particleCount)
+}
+
+// Find mu as in FindMuForLevels, with levels occupied according to
+// FermiDist(beta * (energy - mu)).  The bracket starts from the range of the
+// levels, widened by the temperature, and is expanded until it contains mu.
+func findMuForLevels(energies []float64, degeneracy, beta float64, particleCount int) (float64, error) {
+	if len(energies) == 0 {
+		return 0.0, fmt.Errorf("chemical potential not defined without energy levels")
+	}
 	error := func(mu float64) float64 {
-		return e.NumElectronsError(g, particleCount, mu)
+		return float64(particleCount) - levelOccupation(energies, degeneracy, beta, mu)
+	}
+	// d(error)/d(mu) = -dN/dmu; dN/dmu = beta * sum f (1 - f).  At
+	// beta = +Inf the occupation is a step and the root finder bisects.
+	dError := func(mu float64) float64 {
+		if math.IsInf(beta, 1) {
+			return 0.0
+		}
+		sum := 0.0
+		for _, energy := range energies {
+			f := fermiOccupation(beta, energy-mu)
+			sum += degeneracy * beta * f * (1.0 - f)
+		}
+		return -sum
+	}
+	eMin, eMax := energies[0], energies[0]
+	for _, energy := range energies {
+		eMin, eMax = math.Min(eMin, energy), math.Max(eMax, energy)
+	}
+	muMin, muMax, err := ExpandBracket(error, eMin-1.0/beta, eMax+1.0/beta, 2.0, 64)
+	if err != nil {
+		return 0.0, err
 	}
-	// arbitrary end points; assume error(mu) is monotonic
-	muMin := -100.0 * e.Delta()
-	muMax := 100.0 * e.Delta()
 	eps := 1e-9
-	return Solve1D(error, muMin, muMax, eps, eps)
+	mu, _, err := SafeguardedNewton(error, dError, muMin, muMax, RootOptions{eps, eps, DefaultRootMaxIter})
+	return mu, err
 }
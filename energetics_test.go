@@ -51,8 +51,14 @@ func TestElectronHamiltonian4x4(t *testing.T) {
 	}
 	grid.Iterate(activate)
 	H_el := e.ElectronHamiltonian(grid)
-	alpha_evals, _ := H_el[0].Eigensystem()
-	beta_evals, _ := H_el[1].Eigensystem()
+	alpha_evals, _, err := H_el[0].Eigensystem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	beta_evals, _, err := H_el[1].Eigensystem()
+	if err != nil {
+		t.Fatal(err)
+	}
 	eps := 1e-12
 	neq := func(x float64, y float64) bool {
 		return math.Abs(x-y) > eps
@@ -93,6 +99,27 @@ func TestFermiEnergy2x2(t *testing.T) {
 	}
 }
 
+// A grid without active sites has no electron levels; this is reported as
+// an error rather than aborting in GSL.
+func TestFermiEnergyNoActiveSites(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	grid := NewGridWithDims(2, 2)
+	_, err = e.ElectronEnergies(grid)
+	if _, ok := err.(*GSLError); !ok {
+		t.Fatalf("expected GSLError from ElectronEnergies; got %v", err)
+	}
+	if _, err = e.FermiEnergy(grid, 2); err == nil {
+		t.Fatalf("expected error from FermiEnergy")
+	}
+	if _, err = e.FindMu(grid, 2); err == nil {
+		t.Fatalf("expected error from FindMu")
+	}
+}
+
 func TestFindMu(t *testing.T) {
 	eps := 1e-8
 	neq := func(x float64, y float64) bool {
@@ -119,3 +146,105 @@ func TestFindMu(t *testing.T) {
 		t.Fatalf("unexpected value of mu")
 	}
 }
+
+// Levels far outside [-100 Delta, 100 Delta] still give a chemical potential.
+func TestFindMuDistantLevels(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	offset := 1000.0 * e.Delta()
+	energies := []float64{offset - 1.0, offset, offset + 1.0}
+	mu, err := e.FindMuForLevels(energies, 2.0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// particle-hole symmetric filling puts mu at the middle level
+	if math.Abs(mu-offset) > 1e-6 {
+		t.Fatalf("unexpected chemical potential %v; expected %v", mu, offset)
+	}
+}
+
+// FindMu does not depend on Beta; the temperature enters the level
+// occupations only in Hartree-Fock, where Beta = +Inf gives a step.
+func TestFindMuBeta(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Beta = 20.0
+	e := NewEnergetics(*env)
+	grid := NewGridWithDims(2, 2)
+	grid.Iterate(func(p Point, val bool) {
+		grid.Set(p, true)
+	})
+	mu, err := e.FindMu(grid, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(mu-(-0.45589897859312906)) > 1e-8 {
+		t.Fatalf("unexpected value of mu %v at Beta = %v", mu, env.Beta)
+	}
+	if fermiOccupation(math.Inf(1), 0.0) != 0.5 {
+		t.Fatalf("unexpected occupation at the chemical potential at Beta = +Inf")
+	}
+	mu, err = findMuForLevels([]float64{-1.0, 0.0, 1.0}, 1.0, math.Inf(1), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(mu > -1.0 && mu <= 0.0) {
+		t.Fatalf("unexpected chemical potential %v at Beta = +Inf", mu)
+	}
+}
+
+// Two metallic sites separated by a dimerized site are joined by effective
+// hopping t^2 / (epsilon_metal - epsilon_dimer) when dimers are renormalized.
+func TestEffectiveHoppingThroughDimer(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Renormalize_dimers = true
+	env.Epsilon_dimer = -1.0
+	e := NewEnergetics(*env)
+	grid := NewGridWithDims(3, 1)
+	grid.Set(Point{0, 0}, true)
+	grid.Set(Point{2, 0}, true)
+	H_el := e.ElectronHamiltonian(grid)
+	expected := env.T_alpha * env.T_alpha / (env.Epsilon_alpha - env.Epsilon_dimer)
+	if math.Abs(H_el[0].Get(0, 2)-expected) > 1e-12 {
+		t.Fatalf("unexpected effective hopping %v", H_el[0].Get(0, 2))
+	}
+	if len(e.ElectronClusters(grid)) != 1 || len(grid.AllClusters()) != 2 {
+		t.Fatalf("unexpected electron cluster connectivity")
+	}
+}
+
+// SwapEnergy matches the change in AtomicHamiltonian, including swaps of
+// neighboring sites.
+func TestSwapEnergyMatchesHamiltonian(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	rng := NewRandom(9)
+	grid := RandomFractionGrid(rng, 6, 6, 0.5)
+	for i := 0; i < 200; i++ {
+		p := RandomPoint(rng, grid)
+		q := RandomPoint(rng, grid)
+		if i%2 == 0 {
+			neighbors := grid.Neighbors(p)
+			q = neighbors[rng.Intn(len(neighbors))]
+		}
+		before := e.AtomicHamiltonian(grid)
+		swapEnergy := e.SwapEnergy(grid, p, q)
+		pValue, qValue := grid.Get(p), grid.Get(q)
+		grid.Set(p, qValue)
+		grid.Set(q, pValue)
+		if math.Abs(e.AtomicHamiltonian(grid)-before-swapEnergy) > 1e-12 {
+			t.Fatalf("SwapEnergy %v of %v and %v does not match the Hamiltonian", swapEnergy, p, q)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Ensembles of independent Monte Carlo chains run concurrently.  Each chain
+// is a copy of a template MonteCarlo with its own random stream; chains are
+// run on a fixed number of worker goroutines.  Statistics are merged by
+// taking the time average of each chain and then the mean and standard
+// error over chains, which is valid since the chains are independent.
+package vo2percolation
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+const EnsembleValidateError = "ensemble requires at least one chain, at least one worker and burnIn >= 0"
+const ObservableNameError = "unknown Monte Carlo observable %q"
+
+// Names of the observables in MonteCarloOutput.
+const (
+	ActiveSitesObservable        = "ActiveSites"
+	DimersObservable             = "Dimers"
+	LargestClusterSizeObservable = "LargestClusterSize"
+	EnergyObservable             = "Energy"
+	ConductanceObservable        = "Conductance"
+	ResistivityObservable        = "Resistivity"
+)
+
+// All observables in MonteCarloOutput, in a fixed order.
+var MonteCarloObservables = []string{ActiveSitesObservable, DimersObservable, LargestClusterSizeObservable, EnergyObservable, ConductanceObservable, ResistivityObservable}
+
+// Return the value of the named observable in out.  The second return value
+// is false if out doesn't hold the observable (Conductance and Resistivity
+// are set only with Grid) or if name is not one of MonteCarloObservables.
+func (out *MonteCarloOutput) Observable(name string) (float64, bool) {
+	switch name {
+	case ActiveSitesObservable:
+		return float64(out.ActiveSites), true
+	case DimersObservable:
+		return float64(out.Dimers), true
+	case LargestClusterSizeObservable:
+		return float64(out.LargestClusterSize), true
+	case EnergyObservable:
+		return out.Energy, true
+	case ConductanceObservable:
+		return out.Conductance, out.Grid != nil
+	case ResistivityObservable:
+		return out.Resistivity, out.Grid != nil
+	}
+	return 0.0, false
+}
+
+// Return the time series of the named observable in outputs, skipping
+// outputs which don't hold it.  An unknown name gives an empty series.
+func ObservableSeries(outputs []*MonteCarloOutput, name string) []float64 {
+	series := []float64{}
+	for _, out := range outputs {
+		if val, ok := out.Observable(name); ok {
+			series = append(series, val)
+		}
+	}
+	return series
+}
+
+// Parameters for an ensemble run.
+type Ensemble struct {
+	// Template for each chain; chain i uses the random stream
+	// DeriveSeed(mc.Seed(), i).
+	mc *MonteCarlo
+	// Number of chains and of goroutines running them.
+	chains, workers int
+	// Number of initial sweeps of each chain left out of the statistics.
+	burnIn int
+}
+
+// Mean and standard error of an observable over chains.
+type ObservableSummary struct {
+	Mean, StdErr float64
+	// Number of chains which recorded the observable after burn-in.
+	Chains int
+}
+
+// Result of an ensemble run.
+type EnsembleOutput struct {
+	// Seed and output of each chain.
+	Seeds  []int64
+	Series [][]*MonteCarloOutput
+	// Merged statistics for each name in MonteCarloObservables.
+	Summary map[string]ObservableSummary
+}
+
+// Create a new (input-validated) Ensemble of chains copies of mc, run on
+// workers goroutines.
+func NewEnsemble(mc *MonteCarlo, chains, workers, burnIn int) (*Ensemble, error) {
+	ens := &Ensemble{mc, chains, workers, burnIn}
+	if !ens.validate() {
+		return nil, fmt.Errorf(EnsembleValidateError)
+	}
+	return ens, nil
+}
+
+// Do the fields of ens have acceptable values?
+func (ens *Ensemble) validate() bool {
+	return ens.chains > 0 && ens.workers > 0 && ens.burnIn >= 0
+}
+
+// Run every chain from its own random grid with dimensions (Lx, Ly) and
+// merge the results.  If any chain fails, one of the errors is returned.
+func (ens *Ensemble) Simulate(e *Energetics, Lx, Ly int) (*EnsembleOutput, error) {
+	out := new(EnsembleOutput)
+	out.Seeds = make([]int64, ens.chains)
+	out.Series = make([][]*MonteCarloOutput, ens.chains)
+	errs := make([]error, ens.chains)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < ens.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				chain := ens.mc.withSeed(DeriveSeed(ens.mc.Seed(), i))
+				out.Seeds[i] = chain.Seed()
+				out.Series[i], errs[i] = chain.Simulate(e, Lx, Ly)
+			}
+		}()
+	}
+	for i := 0; i < ens.chains; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	out.Summary = make(map[string]ObservableSummary)
+	for _, name := range MonteCarloObservables {
+		out.Summary[name] = ens.summarize(out.Series, name)
+	}
+	return out, nil
+}
+
+// Return the mean over chains of the time average of the named observable
+// after burn-in, and its standard error.
+func (ens *Ensemble) summarize(series [][]*MonteCarloOutput, name string) ObservableSummary {
+	averages := []float64{}
+	for _, chain := range series {
+		if ens.burnIn >= len(chain) {
+			continue
+		}
+		values := ObservableSeries(chain[ens.burnIn:], name)
+		if len(values) == 0 {
+			continue
+		}
+		averages = append(averages, mean(values))
+	}
+	summary := ObservableSummary{math.NaN(), math.NaN(), len(averages)}
+	if len(averages) == 0 {
+		return summary
+	}
+	summary.Mean = mean(averages)
+	if len(averages) > 1 {
+		summary.StdErr = math.Sqrt(variance(averages) / float64(len(averages)))
+	}
+	return summary
+}
+
+// Return the mean of xs.
+func mean(xs []float64) float64 {
+	sum := 0.0
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+// Return the unbiased sample variance of xs (len(xs) > 1).
+func variance(xs []float64) float64 {
+	m := mean(xs)
+	sum := 0.0
+	for _, x := range xs {
+		sum += (x - m) * (x - m)
+	}
+	return sum / float64(len(xs)-1)
+}
@@ -0,0 +1,72 @@
+package vo2percolation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEnsembleMergesChains(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	mc, err := NewMonteCarlo(1e-12, 30, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc.SetSeed(21)
+	ens, err := NewEnsemble(mc, 6, 3, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ens.Simulate(e, 5, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seeds := make(map[int64]bool)
+	for i, series := range out.Series {
+		if len(series) != 30 {
+			t.Fatalf("chain %d has %d outputs; expected 30", i, len(series))
+		}
+		if seeds[out.Seeds[i]] || series[0].Seed != out.Seeds[i] {
+			t.Fatalf("chain %d does not have its own seed", i)
+		}
+		seeds[out.Seeds[i]] = true
+	}
+	// the summary matches the chain averages computed directly
+	averages := []float64{}
+	for _, series := range out.Series {
+		averages = append(averages, mean(ObservableSeries(series[10:], ActiveSitesObservable)))
+	}
+	summary := out.Summary[ActiveSitesObservable]
+	if summary.Chains != 6 || math.Abs(summary.Mean-mean(averages)) > 1e-12 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	if math.Abs(summary.StdErr-math.Sqrt(variance(averages)/6)) > 1e-12 {
+		t.Fatalf("unexpected standard error %v", summary.StdErr)
+	}
+	// conductance is recorded only on snapshot sweeps 10 and 20
+	if out.Summary[ConductanceObservable].Chains != 6 {
+		t.Fatalf("conductance summary missing chains")
+	}
+	// the same seed reproduces the ensemble
+	again, err := ens.Simulate(e, 5, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Summary[DimersObservable] != out.Summary[DimersObservable] {
+		t.Fatalf("ensemble is not reproducible")
+	}
+}
+
+// Unknown observable names are reported as not held rather than panicking.
+func TestObservableUnknownName(t *testing.T) {
+	out := &MonteCarloOutput{ActiveSites: 3}
+	if _, ok := out.Observable("NotAnObservable"); ok {
+		t.Fatalf("unknown observable reported as held")
+	}
+	if series := ObservableSeries([]*MonteCarloOutput{out}, "NotAnObservable"); len(series) != 0 {
+		t.Fatalf("unexpected series %v for unknown observable", series)
+	}
+}
@@ -16,8 +16,26 @@ type Environment struct {
 	T_alpha      float64 // dimer direction, a_1g orbital
 	T_beta_dimer float64 // dimer direction, e_pi orbital
 	T_beta_diag  float64 // diagonal direction, e_pi orbital
+	// When Renormalize_dimers is true, metallic sites which share an inactive
+	// neighbor are joined by second-order hopping through that site, using
+	// the intermediate-state energy Epsilon_dimer.
+	Renormalize_dimers bool
+	Epsilon_dimer      float64
+	// on-site Hubbard repulsion between electrons in the same orbital
+	U float64
+	// perpendicular magnetic field, in flux quanta per unit area (lengths in
+	// units of the dimer-direction lattice spacing)
+	B float64
+	// resistor network bond resistances; if left unset (zero) they take the
+	// values defaultRMetal and defaultRDimer
+	R_metal float64 // between two active sites
+	R_dimer float64 // touching an inactive site
 }
 
+// Bond resistances used when the environment doesn't give them.
+const defaultRMetal = 1.0
+const defaultRDimer = 1000.0
+
 // Build an Environment from the JSON file at filePath.
 func EnvironmentFromFile(filePath string) (*Environment, error) {
 	return buildEnvironment(func(env *Environment) error {
@@ -39,6 +57,12 @@ func buildEnvironment(copier func(*Environment) error) (*Environment, error) {
 	if err != nil {
 		return nil, err
 	}
+	if env.R_metal == 0 {
+		env.R_metal = defaultRMetal
+	}
+	if env.R_dimer == 0 {
+		env.R_dimer = defaultRDimer
+	}
 	if !env.validate() {
 		return nil, fmt.Errorf(EnvironmentValidateError)
 	}
@@ -47,5 +71,12 @@ func buildEnvironment(copier func(*Environment) error) (*Environment, error) {
 
 // Do the fields of env have acceptable values?
 func (env *Environment) validate() bool {
-	return env.Delta > 0 && env.V > 0 && env.Beta > 0
+	if env.Renormalize_dimers {
+		// effective hopping diverges if the intermediate state is degenerate
+		if env.Epsilon_dimer == env.Epsilon_alpha || env.Epsilon_dimer == env.Epsilon_beta {
+			return false
+		}
+	}
+	resistanceOk := env.R_metal > 0 && env.R_dimer > 0
+	return env.Delta > 0 && env.V > 0 && env.Beta > 0 && resistanceOk
 }
@@ -16,3 +16,26 @@ func TestEnvironmentFromJSON(t *testing.T) {
 		t.Fatalf("incorrect value in Environment")
 	}
 }
+
+// Renormalizing dimers requires a nondegenerate intermediate state.
+func TestEnvironmentDegenerateDimerEnergy(t *testing.T) {
+	data := "{\"Delta\":1, \"V\":0.5, \"Beta\":1, \"Epsilon_alpha\":1, \"Renormalize_dimers\":true, \"Epsilon_dimer\":1}"
+	if _, err := EnvironmentFromString(data); err == nil {
+		t.Fatalf("accepted degenerate dimer energy")
+	}
+}
+
+// Unset bond resistances take their defaults; negative ones are rejected.
+func TestEnvironmentResistances(t *testing.T) {
+	env, err := EnvironmentFromString("{\"Delta\":1, \"V\":0.5, \"Beta\":1}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.R_metal != defaultRMetal || env.R_dimer != defaultRDimer {
+		t.Fatalf("unexpected default resistances %v, %v", env.R_metal, env.R_dimer)
+	}
+	data := "{\"Delta\":1, \"V\":0.5, \"Beta\":1, \"R_metal\":-1}"
+	if _, err := EnvironmentFromString(data); err == nil {
+		t.Fatalf("accepted negative R_metal")
+	}
+}
@@ -0,0 +1,150 @@
+// Detection of the equilibration (burn-in) phase of Monte Carlo runs.  The
+// sweeps before the detected burn-in are left out of the production data.
+// Two rules are available:
+//
+// MSERRule runs one chain and applies the marginal standard error rule
+// (MSER-5) to its Energy and ActiveSites series.
+//
+// TwoChainRule runs one chain from DimerizedGrid and one from MetallicGrid;
+// they approach equilibrium from opposite sides, so the burn-in is the first
+// sweep at which their Energy and ActiveSites series have crossed.
+//
+// In both cases the burn-in is the larger of those found for the two series.
+package vo2percolation
+
+import (
+	"fmt"
+	"math"
+)
+
+const EquilibrationRuleError = "unknown equilibration rule %d"
+const EquilibrationError = "%s series did not equilibrate within %d sweeps"
+
+// Burn-in criterion used by SimulateEquilibrated.
+type EquilibrationRule int
+
+const (
+	MSERRule EquilibrationRule = iota
+	TwoChainRule
+)
+
+// Series used to detect equilibration.
+var equilibrationObservables = []string{EnergyObservable, ActiveSitesObservable}
+
+// Number of samples per batch in MSERBurnIn.
+const mserBatchSize = 5
+
+// Result of SimulateEquilibrated.
+type EquilibratedRun struct {
+	// Detected burn-in length in sweeps.
+	BurnIn int
+	// Full output of each chain (one chain for MSERRule, two for
+	// TwoChainRule: the dimerized start, then the metallic start).
+	Series [][]*MonteCarloOutput
+	// Output of each chain from sweep BurnIn on.
+	Production [][]*MonteCarloOutput
+}
+
+// Return the MSER-5 burn-in of xs: the series is divided into batches of
+// mserBatchSize samples, and the burn-in is the number of samples d (a
+// multiple of the batch size) which minimizes the marginal standard error
+// var(batches after d) / (batches after d).  The minimum is searched in the
+// first half of the series.  ok is false if the minimum is at the end of the
+// search range, in which case the series is too short to have equilibrated.
+func MSERBurnIn(xs []float64) (int, bool) {
+	batches := []float64{}
+	for i := 0; i+mserBatchSize <= len(xs); i += mserBatchSize {
+		batches = append(batches, mean(xs[i:i+mserBatchSize]))
+	}
+	if len(batches) < 4 {
+		return 0, false
+	}
+	best, bestD := math.Inf(1), 0
+	maxD := len(batches) / 2
+	for d := 0; d <= maxD; d++ {
+		rest := batches[d:]
+		m := mean(rest)
+		sum := 0.0
+		for _, x := range rest {
+			sum += (x - m) * (x - m)
+		}
+		mser := sum / (float64(len(rest)) * float64(len(rest)))
+		if mser < best {
+			best, bestD = mser, d
+		}
+	}
+	return bestD * mserBatchSize, bestD < maxD
+}
+
+// Return the first index at which the series a and b have crossed (or
+// touched) since index 0.  ok is false if they never cross.
+func CrossingTime(a, b []float64) (int, bool) {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	if n == 0 {
+		return 0, false
+	}
+	initial := a[0] - b[0]
+	for t := 0; t < n; t++ {
+		diff := a[t] - b[t]
+		if diff == 0 || (diff > 0) != (initial > 0) {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// Run a simulation from grids with dimensions (Lx, Ly), taking mc.totalSweeps
+// sweeps per chain, and detect the burn-in with rule.  Returns an error if
+// the run is too short to equilibrate.  The chains of TwoChainRule use the
+// random streams DeriveSeed(mc.Seed(), 0) and DeriveSeed(mc.Seed(), 1).
+func (mc *MonteCarlo) SimulateEquilibrated(e *Energetics, Lx, Ly int, rule EquilibrationRule) (*EquilibratedRun, error) {
+	run := new(EquilibratedRun)
+	switch rule {
+	case MSERRule:
+		series, err := mc.Simulate(e, Lx, Ly)
+		if err != nil {
+			return nil, err
+		}
+		run.Series = [][]*MonteCarloOutput{series}
+		for _, name := range equilibrationObservables {
+			burnIn, ok := MSERBurnIn(ObservableSeries(series, name))
+			if !ok {
+				return nil, fmt.Errorf(EquilibrationError, name, mc.totalSweeps)
+			}
+			run.BurnIn = maxInt(run.BurnIn, burnIn)
+		}
+	case TwoChainRule:
+		starts := []*Grid{DimerizedGrid(Lx, Ly), MetallicGrid(Lx, Ly)}
+		for i, grid := range starts {
+			series, err := mc.withSeed(DeriveSeed(mc.Seed(), i)).SimulateFrom(e, grid)
+			if err != nil {
+				return nil, err
+			}
+			run.Series = append(run.Series, series)
+		}
+		for _, name := range equilibrationObservables {
+			burnIn, ok := CrossingTime(ObservableSeries(run.Series[0], name), ObservableSeries(run.Series[1], name))
+			if !ok {
+				return nil, fmt.Errorf(EquilibrationError, name, mc.totalSweeps)
+			}
+			run.BurnIn = maxInt(run.BurnIn, burnIn)
+		}
+	default:
+		return nil, fmt.Errorf(EquilibrationRuleError, rule)
+	}
+	for _, series := range run.Series {
+		run.Production = append(run.Production, series[run.BurnIn:])
+	}
+	return run, nil
+}
+
+// Return the larger of a and b.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,70 @@
+package vo2percolation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMSERBurnInTransient(t *testing.T) {
+	// noise around zero after a transient decaying from 50
+	rng := NewRandom(8)
+	xs := make([]float64, 2000)
+	for i := range xs {
+		xs[i] = 50*math.Exp(-float64(i)/20) + rng.NormFloat64()
+	}
+	burnIn, ok := MSERBurnIn(xs)
+	if !ok || burnIn < 50 || burnIn > 200 {
+		t.Fatalf("unexpected MSER burn-in %v (ok = %v)", burnIn, ok)
+	}
+	// a series that is still drifting has not equilibrated
+	for i := range xs {
+		xs[i] = float64(i) + rng.NormFloat64()
+	}
+	if _, ok := MSERBurnIn(xs); ok {
+		t.Fatalf("drifting series reported as equilibrated")
+	}
+}
+
+func TestCrossingTime(t *testing.T) {
+	a := []float64{0, 1, 2, 3, 4}
+	b := []float64{5, 4, 3, 3, 2}
+	if tc, ok := CrossingTime(a, b); !ok || tc != 3 {
+		t.Fatalf("unexpected crossing time %v (ok = %v)", tc, ok)
+	}
+	if _, ok := CrossingTime(a, []float64{9, 9, 9, 9, 9}); ok {
+		t.Fatalf("reported a crossing of separated series")
+	}
+}
+
+func TestSimulateEquilibrated(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	mc, err := NewMonteCarlo(1e-12, 300, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc.SetSeed(12)
+	for rule, chains := range map[EquilibrationRule]int{MSERRule: 1, TwoChainRule: 2} {
+		run, err := mc.SimulateEquilibrated(e, 6, 6, rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(run.Series) != chains || len(run.Production) != chains {
+			t.Fatalf("rule %v gave %d chains; expected %d", rule, len(run.Series), chains)
+		}
+		for i, series := range run.Series {
+			if len(run.Production[i]) != 300-run.BurnIn || run.Production[i][0] != series[run.BurnIn] {
+				t.Fatalf("rule %v: production does not start at burn-in %d", rule, run.BurnIn)
+			}
+			if series[0].Energy != env.Delta*float64(series[0].ActiveSites)-env.V*float64(series[0].Dimers) {
+				t.Fatalf("recorded energy does not match the atomic Hamiltonian")
+			}
+		}
+	}
+	if _, err := mc.SimulateEquilibrated(e, 6, 6, EquilibrationRule(7)); err == nil {
+		t.Fatalf("accepted unknown equilibration rule")
+	}
+}
@@ -9,6 +9,7 @@ package vo2percolation
 import (
 	"fmt"
 	"math"
+	"math/rand"
 )
 
 const GridShapeError = "Grid data must be rectangular and contain at least one point"
@@ -68,8 +69,9 @@ func NewGridWithDims(Lx, Ly int) *Grid {
 	return g
 }
 
-// Generate a random grid of dimensions Lx and Ly with N active sites.
-func RandomConstrainedGrid(Lx, Ly, N int) (*Grid, error) {
+// Generate a random grid of dimensions Lx and Ly with N active sites, using
+// the generator rng.
+func RandomConstrainedGrid(rng *rand.Rand, Lx, Ly, N int) (*Grid, error) {
 	// must have at least one site
 	if Lx <= 0 || Ly <= 0 {
 		return nil, fmt.Errorf("invalid grid dimensions")
@@ -84,7 +86,7 @@ func RandomConstrainedGrid(Lx, Ly, N int) (*Grid, error) {
 	grid := NewGridWithDims(Lx, Ly)
 	// activate random sites
 	for activeCount := 0; activeCount < N; {
-		p := RandomPoint(grid)
+		p := RandomPoint(rng, grid)
 		if !grid.Get(p) {
 			grid.Set(p, true)
 			activeCount += 1
@@ -309,11 +311,18 @@ func (g *Grid) ActiveSites() *PointSet {
 // That method is the 'Hoshen-Kopelman algorithm'. See:
 // http://www.ocf.berkeley.edu/~fricke/projects/hoshenkopelman/hoshenkopelman.html
 func (g *Grid) AllClusters() []*PointSet {
+	return g.AllClustersWith(g.Neighbors)
+}
+
+// Return a slice containing each cluster of active sites on the grid, where
+// sites are connected when one is in the list given by neighbors for the
+// other.
+func (g *Grid) AllClustersWith(neighbors func(Point) []Point) []*PointSet {
 	clusters := []*PointSet{}
 	unexplored := g.ActiveSites()
 	for unexplored.Size() > 0 {
 		p := unexplored.Point()
-		thisCluster := g.Cluster(p)
+		thisCluster := g.ClusterWith(p, neighbors)
 		for _, clusterPoint := range thisCluster.Elements() {
 			unexplored.Remove(clusterPoint)
 		}
@@ -337,15 +346,46 @@ func (g *Grid) LargestCluster() *PointSet {
 	return max
 }
 
+// Return the size of the largest cluster on the grid (0 if there are no
+// active sites).
+func (g *Grid) LargestClusterSize() int {
+	largest := g.LargestCluster()
+	if largest == nil {
+		return 0
+	}
+	return largest.Size()
+}
+
+// Return true if some cluster joins the left (x = 0) and right (x = Lx - 1)
+// edges of the grid.
+func (g *Grid) Spans() bool {
+	for _, cluster := range g.AllClusters() {
+		left, right := false, false
+		for _, p := range cluster.Elements() {
+			left = left || p.X() == 0
+			right = right || p.X() == g.Lx()-1
+		}
+		if left && right {
+			return true
+		}
+	}
+	return false
+}
+
 // Return the cluster at (x, y).
 func (g *Grid) Cluster(p Point) *PointSet {
+	return g.ClusterWith(p, g.Neighbors)
+}
+
+// Return the cluster at (x, y), using neighbors to connect sites.
+func (g *Grid) ClusterWith(p Point, neighbors func(Point) []Point) *PointSet {
 	ps := g.PointSet()
-	g.clusterHelper(p, ps)
+	g.clusterHelper(p, ps, neighbors)
 	return ps
 }
 
 // Add all members of the cluster at (x, y) to ps.
-func (g *Grid) clusterHelper(start Point, ps *PointSet) {
+func (g *Grid) clusterHelper(start Point, ps *PointSet, neighbors func(Point) []Point) {
 	if ps == nil {
 		panic("must initialize ps in clusterHelper")
 	}
@@ -355,10 +395,10 @@ func (g *Grid) clusterHelper(start Point, ps *PointSet) {
 	}
 	// active site, not seen yet: add it and try its neighbors
 	ps.Add(start)
-	ns := g.Neighbors(start)
+	ns := neighbors(start)
 	for _, point := range ns {
 		if !ps.Contains(point) {
-			g.clusterHelper(point, ps)
+			g.clusterHelper(point, ps, neighbors)
 		}
 	}
 }
@@ -373,6 +413,24 @@ func (g *Grid) Neighbors(p Point) []Point {
 	return ns
 }
 
+// Return the neighbors of the given point, plus the active sites which can be
+// reached through a single inactive neighbor.  Electrons connect these sites
+// by effective hopping through the dimerized site.
+func (g *Grid) RenormalizedNeighbors(p Point) []Point {
+	ns := g.Neighbors(p)
+	for _, d := range g.Neighbors(p) {
+		if g.Get(d) {
+			continue
+		}
+		for _, n := range g.Neighbors(d) {
+			if n != p && g.Get(n) {
+				ns = append(ns, n)
+			}
+		}
+	}
+	return ns
+}
+
 // Return the dimer-direction neighbors of the given point.
 func (g *Grid) DimerNeighbors(p Point) []Point {
 	ns := []Point{}
@@ -432,6 +490,48 @@ func (g *Grid) DiagNeighbors(p Point) []Point {
 	return ns
 }
 
+// Return the sublattice (0, 1 or 2) of the site p.  The three sublattices of
+// the triangular lattice are chosen so that no two neighbors share one: in
+// axial coordinates q = x - floor(y / 2), r = y the sublattice is
+// (q - r) mod 3.
+func SublatticeColor(p Point) int {
+	q := p.X() - p.Y()/2
+	c := (q - p.Y()) % 3
+	if c < 0 {
+		c += 3
+	}
+	return c
+}
+
+// Return the sites of g on each of the three sublattices, row by row.
+func (g *Grid) Sublattices() [][]Point {
+	sublattices := make([][]Point, 3)
+	for y := 0; y < g.Ly(); y++ {
+		for x := 0; x < g.Lx(); x++ {
+			p := Point{x, y}
+			c := SublatticeColor(p)
+			sublattices[c] = append(sublattices[c], p)
+		}
+	}
+	return sublattices
+}
+
+// Spacing between neighboring rows of the lattice, in units of the spacing
+// between neighbors in the dimer direction.  With this choice all six
+// neighbors of a site are equidistant.
+const RowSpacing = 0.8660254037844386 // sqrt(3) / 2
+
+// Return the physical position of the site p on the rhombic lattice.  Odd
+// rows are shifted by half a lattice spacing in the dimer direction relative
+// to even rows.
+func (g *Grid) Position(p Point) (float64, float64) {
+	x := float64(p.X())
+	if p.Y()%2 == 1 {
+		x += 0.5
+	}
+	return x, float64(p.Y()) * RowSpacing
+}
+
 // Return a string representation of the grid.
 func (g *Grid) String() string {
 	return fmt.Sprintln(g.data)
@@ -91,7 +91,7 @@ func TestGridClusters(t *testing.T) {
 func TestRandomConstrainedGridCreation(t *testing.T) {
 	activeSites := 128
 	L := 64
-	grid, err := RandomConstrainedGrid(L, L, activeSites)
+	grid, err := RandomConstrainedGrid(NewRandom(1), L, L, activeSites)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -118,6 +118,42 @@ func TestNextGridNumber(t *testing.T) {
 	}
 }
 
+// No site shares a sublattice with any of its neighbors.
+func TestSublatticesSeparateNeighbors(t *testing.T) {
+	grid := NewGridWithDims(7, 6)
+	count := 0
+	for c, sublattice := range grid.Sublattices() {
+		for _, p := range sublattice {
+			count++
+			for _, n := range grid.Neighbors(p) {
+				if SublatticeColor(n) == c {
+					t.Fatalf("neighbors %v and %v share sublattice %d", p, n, c)
+				}
+			}
+		}
+	}
+	if count != 42 {
+		t.Fatalf("sublattices hold %d sites; expected 42", count)
+	}
+}
+
+func TestGridSpans(t *testing.T) {
+	grid := NewGridWithDims(4, 3)
+	if grid.Spans() || grid.LargestClusterSize() != 0 {
+		t.Fatalf("empty grid spans or has clusters")
+	}
+	for x := 0; x < 4; x++ {
+		grid.Set(Point{x, 1}, true)
+	}
+	if !grid.Spans() {
+		t.Fatalf("full row does not span the grid")
+	}
+	grid.Set(Point{2, 1}, false)
+	if grid.Spans() {
+		t.Fatalf("broken row spans the grid")
+	}
+}
+
 // The performance of AllClusters should scale linearly with the number of
 // sites in the grid.  Does it?
 // (lots of code stolen from PointSetPerformance test, may want a common
@@ -144,7 +180,7 @@ func TestAllClustersPerformance(t *testing.T) {
 		// A constant fraction of these sites are active.
 		activeN := int(activeFraction * float64(N))
 		L := int(math.Ceil(math.Sqrt(float64(N))))
-		grid, err := RandomConstrainedGrid(L, L, activeN)
+		grid, err := RandomConstrainedGrid(NewRandom(int64(L)), L, L, activeN)
 		if err != nil {
 			t.Fatal(err)
 		}
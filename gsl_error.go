@@ -0,0 +1,86 @@
+// Conversion of GSL errors to Go errors.  GSL's default error handler aborts
+// the process; it is replaced here by a handler which records the error in
+// thread-local storage.  GSL calls are made through withGSLErrors, which
+// pins the calling goroutine to its OS thread so that the recorded error can
+// be read back after the call.
+package vo2percolation
+
+/*
+#cgo LDFLAGS: -lgsl -lgslcblas
+#include <gsl/gsl_errno.h>
+
+static __thread int lastErrno = 0;
+static __thread const char *lastReason = 0;
+static __thread const char *lastFile = 0;
+static __thread int lastLine = 0;
+
+static void recordError(const char *reason, const char *file, int line, int gsl_errno) {
+	if (lastErrno != 0) {
+		// keep the first error
+		return;
+	}
+	lastErrno = gsl_errno;
+	lastReason = reason;
+	lastFile = file;
+	lastLine = line;
+}
+
+static void installErrorHandler(void) {
+	gsl_set_error_handler(&recordError);
+}
+
+static void clearError(void) {
+	lastErrno = 0;
+	lastReason = 0;
+	lastFile = 0;
+	lastLine = 0;
+}
+
+static int lastErrorErrno(void) { return lastErrno; }
+static const char *lastErrorReason(void) { return lastReason; }
+static const char *lastErrorFile(void) { return lastFile; }
+static int lastErrorLine(void) { return lastLine; }
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func init() {
+	C.installErrorHandler()
+}
+
+// Error reported by a GSL routine.
+type GSLError struct {
+	Status int    // GSL error code (e.g. GSL_EINVAL)
+	Reason string // description given where the error was raised
+	File   string
+	Line   int
+}
+
+func (err *GSLError) Error() string {
+	message := C.GoString(C.gsl_strerror(C.int(err.Status)))
+	if err.File == "" {
+		return fmt.Sprintf("gsl: %s: %s", message, err.Reason)
+	}
+	return fmt.Sprintf("gsl: %s: %s (%s:%d)", message, err.Reason, err.File, err.Line)
+}
+
+// Make the GSL calls in f and return the first error they raised, or a
+// GSLError for the status returned by f if no error was raised.  f returns
+// a GSL status code (GSL_SUCCESS = 0 for success).
+func withGSLErrors(f func() C.int) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	C.clearError()
+	status := f()
+	if errno := C.lastErrorErrno(); errno != 0 {
+		return &GSLError{int(errno), C.GoString(C.lastErrorReason()), C.GoString(C.lastErrorFile()), int(C.lastErrorLine())}
+	}
+	if status != 0 {
+		return &GSLError{int(status), "routine returned failure status", "", 0}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+// Unrestricted Hartree-Fock mean-field treatment of an on-site Hubbard
+// repulsion U for the electrons on the metallic network.  Electrons of spin s
+// in orbital o at site i feel the potential U n(i, o, -s), where n is the
+// density of the opposite spin in the same orbital.  The densities are found
+// self-consistently by linear mixing.  Densities are indexed like the rows of
+// the ElectronHamiltonian blocks: the row of local orbital l at the site with
+// 1D index id is l * Lx * Ly + id.
+package vo2percolation
+
+import (
+	"fmt"
+	"math"
+)
+
+const HartreeFockValidateError = "Hartree-Fock input parameters are invalid"
+const HartreeFockParticleError = "Hartree-Fock requires active sites and a positive number of particles"
+
+// Parameters for the self-consistency loop.
+type HartreeFock struct {
+	// Fraction of the newly computed densities mixed into the old densities
+	// at each iteration (0 < mixing <= 1).
+	mixing float64
+	// Converged when no density changes by more than tolerance.
+	tolerance float64
+	// Maximum number of iterations.
+	maxIter int
+	// Size of the initial local moments, which alternate in sign between
+	// even and odd x to break the spin symmetry.
+	initialMoment float64
+}
+
+// Mean-field solution for a single grid.
+type HartreeFockResult struct {
+	Converged bool
+	// Chemical potential of the final iteration.
+	Mu float64
+	// Mean-field total energy, including the double-counting correction.
+	TotalEnergy float64
+	// Density[s][b][i] is the density of spin s (0 = up, 1 = down) on row
+	// i of block b of the electron Hamiltonian.
+	Density [2][][]float64
+	// Local moment n_up - n_down at each site, summed over orbitals.
+	LocalMoments []float64
+	// State after each iteration.
+	History []HartreeFockStep
+}
+
+// Convergence information recorded at each iteration.
+type HartreeFockStep struct {
+	// Largest change in any density.
+	MaxDensityChange float64
+	TotalEnergy      float64
+}
+
+// Create a new (input-validated) HartreeFock with the given parameters.
+func NewHartreeFock(mixing, tolerance float64, maxIter int, initialMoment float64) (*HartreeFock, error) {
+	hf := new(HartreeFock)
+	hf.mixing = mixing
+	hf.tolerance = tolerance
+	hf.maxIter = maxIter
+	hf.initialMoment = initialMoment
+	if !hf.validate() {
+		return nil, fmt.Errorf(HartreeFockValidateError)
+	}
+	return hf, nil
+}
+
+// Do the fields of hf have acceptable values?
+func (hf *HartreeFock) validate() bool {
+	return hf.mixing > 0 && hf.mixing <= 1 && hf.tolerance > 0 && hf.maxIter > 0
+}
+
+// Find the self-consistent densities for particleCount electrons on g.
+// If the densities do not converge within the iteration limit, the result
+// of the final iteration is returned with Converged = false.
+func (hf *HartreeFock) Solve(e *Energetics, g *Grid, particleCount int) (*HartreeFockResult, error) {
+	activePoints := g.ActiveSites().Elements()
+	if particleCount <= 0 || len(activePoints) == 0 {
+		return nil, fmt.Errorf(HartreeFockParticleError)
+	}
+	U := e.env.U
+	N := g.Lx() * g.Ly()
+	convert := g.ConvertTo1D()
+	blocks := e.model.Blocks()
+	numBlocks := len(blocks)
+	// rows of each block which belong to active sites
+	activeRows := make([][]int, numBlocks)
+	rowSign := make(map[int]float64)
+	for b, orbs := range blocks {
+		for l := range orbs {
+			for _, p := range activePoints {
+				row := l*N + convert(p)
+				activeRows[b] = append(activeRows[b], row)
+				rowSign[row] = 1.0
+				if p.X()%2 == 1 {
+					rowSign[row] = -1.0
+				}
+			}
+		}
+	}
+	// initial densities: uniform filling plus alternating moments
+	result := new(HartreeFockResult)
+	density := &result.Density
+	fill := float64(particleCount) / float64(2*len(e.model.Orbitals)*len(activePoints))
+	for s := 0; s < 2; s++ {
+		spinSign := 1.0 - 2.0*float64(s)
+		density[s] = make([][]float64, numBlocks)
+		for b, orbs := range blocks {
+			density[s][b] = make([]float64, len(orbs)*N)
+			for _, row := range activeRows[b] {
+				density[s][b][row] = fill + spinSign*rowSign[row]*hf.initialMoment/2.0
+			}
+		}
+	}
+	for iter := 0; iter < hf.maxIter; iter++ {
+		// diagonalize the mean-field Hamiltonian of each spin and block
+		levels := []float64{}
+		evals := [2][][]float64{}
+		evecs := [2][][][]float64{}
+		for s := 0; s < 2; s++ {
+			H := e.ElectronHamiltonian(g)
+			evals[s] = make([][]float64, numBlocks)
+			evecs[s] = make([][][]float64, numBlocks)
+			for b := 0; b < numBlocks; b++ {
+				for _, row := range activeRows[b] {
+					H[b].Add(row, row, U*density[1-s][b][row])
+				}
+				vals, vecs, err := H[b].Eigensystem()
+				if err != nil {
+					return nil, err
+				}
+				evals[s][b], evecs[s][b] = vals, nonzeroVectors(vecs)
+				levels = append(levels, vals...)
+			}
+		}
+		mu, err := findMuForLevels(levels, 1.0, e.Beta(), particleCount)
+		if err != nil {
+			return nil, err
+		}
+		// band energy and new densities
+		bandEnergy := 0.0
+		newDensity := [2][][]float64{}
+		for s := 0; s < 2; s++ {
+			newDensity[s] = make([][]float64, numBlocks)
+			for b := 0; b < numBlocks; b++ {
+				newDensity[s][b] = make([]float64, len(density[s][b]))
+				for k, energy := range evals[s][b] {
+					f := fermiOccupation(e.Beta(), energy-mu)
+					bandEnergy += f * energy
+					for i, val := range evecs[s][b][k] {
+						newDensity[s][b][i] += f * val * val
+					}
+				}
+			}
+		}
+		// double-counting correction uses the densities which built H
+		doubleCounting := 0.0
+		for b := 0; b < numBlocks; b++ {
+			for i := range density[0][b] {
+				doubleCounting += U * density[0][b][i] * density[1][b][i]
+			}
+		}
+		// mix the old and new densities
+		maxChange := 0.0
+		for s := 0; s < 2; s++ {
+			for b := 0; b < numBlocks; b++ {
+				for i := range density[s][b] {
+					change := newDensity[s][b][i] - density[s][b][i]
+					maxChange = math.Max(maxChange, math.Abs(change))
+					density[s][b][i] += hf.mixing * change
+				}
+			}
+		}
+		result.Mu = mu
+		result.TotalEnergy = bandEnergy - doubleCounting
+		result.History = append(result.History, HartreeFockStep{maxChange, result.TotalEnergy})
+		if maxChange < hf.tolerance {
+			result.Converged = true
+			break
+		}
+	}
+	result.LocalMoments = make([]float64, N)
+	for b := 0; b < numBlocks; b++ {
+		for i := range density[0][b] {
+			result.LocalMoments[i%N] += density[0][b][i] - density[1][b][i]
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package vo2percolation
+
+import (
+	"math"
+	"testing"
+)
+
+// Without interactions the solution is reached immediately and carries no
+// moments, and the densities add up to the particle count.
+func TestHartreeFockNoInteraction(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	hf, err := NewHartreeFock(0.5, 1e-8, 100, 0.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grid := activeGrid(3, 2)
+	result, err := hf.Solve(e, grid, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Converged {
+		t.Fatalf("Hartree-Fock did not converge")
+	}
+	total := 0.0
+	for s := 0; s < 2; s++ {
+		for _, orbital := range result.Density[s] {
+			for _, n := range orbital {
+				total += n
+			}
+		}
+	}
+	if math.Abs(total-6.0) > 1e-6 {
+		t.Fatalf("densities sum to %v instead of the particle count", total)
+	}
+	for _, m := range result.LocalMoments {
+		if math.Abs(m) > 1e-6 {
+			t.Fatalf("unexpected local moment without interactions")
+		}
+	}
+}
+
+// A single electron on an isolated site with strong repulsion and low
+// temperature forms a full local moment.
+func TestHartreeFockLocalMoment(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.U = 4.0
+	env.Beta = 20.0
+	e := NewEnergetics(*env)
+	hf, err := NewHartreeFock(0.5, 1e-8, 500, 0.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grid := NewGridWithDims(3, 1)
+	grid.Set(Point{0, 0}, true)
+	result, err := hf.Solve(e, grid, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Converged {
+		t.Fatalf("Hartree-Fock did not converge")
+	}
+	if result.LocalMoments[0] < 0.9 {
+		t.Fatalf("unexpected local moment %v", result.LocalMoments[0])
+	}
+}
@@ -0,0 +1,214 @@
+// Complex Hermitian matrix with GSL eigendecomposition, used for electron
+// Hamiltonians which are not real (e.g. in a magnetic field).
+package vo2percolation
+
+/*
+#cgo LDFLAGS: -lgsl -lgslcblas
+#include <gsl/gsl_errno.h>
+#include <gsl/gsl_math.h>
+#include <gsl/gsl_complex.h>
+#include <gsl/gsl_complex_math.h>
+#include <gsl/gsl_vector.h>
+#include <gsl/gsl_matrix.h>
+#include <gsl/gsl_eigen.h>
+
+static void hermitian_set(gsl_matrix_complex *m, size_t i, size_t j, double re, double im) {
+	gsl_matrix_complex_set(m, i, j, gsl_complex_rect(re, im));
+}
+
+static double hermitian_real(const gsl_matrix_complex *m, size_t i, size_t j) {
+	return GSL_REAL(gsl_matrix_complex_get(m, i, j));
+}
+
+static double hermitian_imag(const gsl_matrix_complex *m, size_t i, size_t j) {
+	return GSL_IMAG(gsl_matrix_complex_get(m, i, j));
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"math/cmplx"
+)
+
+type HermitianMatrix struct {
+	// dimensions (square, so lx = ly)
+	length int
+	// Data is a map since many rows/cols may be empty.
+	// Only the upper triangle (row <= column) is stored.
+	data map[int]map[int]complex128
+}
+
+// Return a zeroed LxL Hermitian matrix.
+func NewHermitianMatrix(L int) *HermitianMatrix {
+	herm := new(HermitianMatrix)
+	herm.length = L
+	herm.data = make(map[int]map[int]complex128)
+	return herm
+}
+
+// Return the length of the matrix represented by herm.
+func (herm *HermitianMatrix) Length() int {
+	return herm.length
+}
+
+// Return the value at row i, column j in herm.
+func (herm *HermitianMatrix) Get(i, j int) complex128 {
+	if i < 0 || i >= herm.length || j < 0 || j >= herm.length {
+		panic("matrix access out of bounds")
+	}
+	if i > j {
+		return cmplx.Conj(herm.Get(j, i))
+	}
+	row, ok := herm.data[i]
+	if !ok {
+		return 0
+	}
+	return row[j]
+}
+
+// Set the value at row i, column j in herm to val.  The value at row j,
+// column i becomes the conjugate of val.  Diagonal values must be real.
+func (herm *HermitianMatrix) Set(i, j int, val complex128) {
+	if i < 0 || i >= herm.length || j < 0 || j >= herm.length {
+		panic("matrix access out of bounds")
+	}
+	if i == j && imag(val) != 0 {
+		panic("Hermitian matrix diagonal must be real")
+	}
+	if i > j {
+		i, j, val = j, i, cmplx.Conj(val)
+	}
+	row, ok := herm.data[i]
+	if !ok {
+		// row doesn't exist yet, need to create it
+		row = make(map[int]complex128)
+		herm.data[i] = row
+	}
+	row[j] = val
+}
+
+// Add x to the value at row i, column j in herm (and the conjugate of x to
+// the value at row j, column i).
+func (herm *HermitianMatrix) Add(i, j int, x complex128) {
+	cur := herm.Get(i, j)
+	herm.Set(i, j, cur+x)
+}
+
+// Return a new HermitianMatrix without the empty rows (and columns) in herm.
+// The map returned converts from row indices in the returned matrix to row
+// indices in the original matrix.
+func (herm *HermitianMatrix) RemoveEmptyRows() (*HermitianMatrix, map[int]int) {
+	nonEmpty, convert := make([]bool, herm.length), make(map[int]int)
+	// Build a list of non-empty rows.
+	for i, row := range herm.data {
+		for j, val := range row {
+			if val != 0 {
+				nonEmpty[i] = true
+				nonEmpty[j] = true
+			}
+		}
+	}
+	// Build the map from the old indexing to the indexing without empty
+	// rows.
+	iNew := 0
+	for iOld, val := range nonEmpty {
+		if val {
+			convert[iNew] = iOld
+			iNew++
+		}
+	}
+	newMatrix := NewHermitianMatrix(iNew)
+	for i := 0; i < iNew; i++ {
+		for j := i; j < iNew; j++ {
+			val := herm.Get(convert[i], convert[j])
+			if val != 0 {
+				newMatrix.Set(i, j, val)
+			}
+		}
+	}
+	return newMatrix, convert
+}
+
+// Return a slice of the eigenvalues of herm, and a slice of the
+// eigenvectors.  As with SymmetricMatrix.Eigensystem, the eigenvectors are
+// padded to the full size of herm: the eigenvector of the k'th eigenvalue is
+// the k'th nonzero vector.  Returns a GSLError if GSL fails.
+func (herm *HermitianMatrix) Eigensystem() ([]float64, [][]complex128, error) {
+	originalSize := herm.length
+	reduced, convert := herm.RemoveEmptyRows()
+	size := C.size_t(reduced.length)
+	var eigenvalues *C.gsl_vector
+	var eigenvectors, matrix *C.gsl_matrix_complex
+	var work *C.gsl_eigen_hermv_workspace
+	defer func() {
+		if eigenvalues != nil {
+			C.gsl_vector_free(eigenvalues)
+		}
+		if eigenvectors != nil {
+			C.gsl_matrix_complex_free(eigenvectors)
+		}
+		if matrix != nil {
+			C.gsl_matrix_complex_free(matrix)
+		}
+		if work != nil {
+			C.gsl_eigen_hermv_free(work)
+		}
+	}()
+	err := withGSLErrors(func() C.int {
+		eigenvalues = C.gsl_vector_alloc(size)
+		eigenvectors = C.gsl_matrix_complex_alloc(size, size)
+		work = C.gsl_eigen_hermv_alloc(size)
+		if eigenvalues == nil || eigenvectors == nil || work == nil {
+			return C.GSL_ENOMEM
+		}
+		matrix = reduced.toMatrix()
+		return C.gsl_eigen_hermv(matrix, eigenvalues, eigenvectors, work)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	goEigenvalues := vectorToSlice(eigenvalues)
+	// pad the eigenvectors back to full size
+	retEigenvectors := make([][]complex128, originalSize)
+	for i := range retEigenvectors {
+		retEigenvectors[i] = make([]complex128, originalSize)
+	}
+	var i, j C.size_t
+	for i = 0; i < size; i++ {
+		for j = 0; j < size; j++ {
+			re := float64(C.hermitian_real(eigenvectors, j, i))
+			im := float64(C.hermitian_imag(eigenvectors, j, i))
+			retEigenvectors[convert[int(i)]][convert[int(j)]] = complex(re, im)
+		}
+	}
+	return goEigenvalues, retEigenvectors, nil
+}
+
+// Return the GSL matrix representation of herm.
+func (herm *HermitianMatrix) toMatrix() *C.gsl_matrix_complex {
+	size := C.size_t(herm.length)
+	matrix := C.gsl_matrix_complex_calloc(size, size)
+	for i, row := range herm.data {
+		for j, val := range row {
+			it, jt := C.size_t(i), C.size_t(j)
+			C.hermitian_set(matrix, it, jt, C.double(real(val)), C.double(imag(val)))
+			if i != j {
+				C.hermitian_set(matrix, jt, it, C.double(real(val)), C.double(-imag(val)))
+			}
+		}
+	}
+	return matrix
+}
+
+func (herm *HermitianMatrix) String() string {
+	out := ""
+	for i := 0; i < herm.length; i++ {
+		outRow := ""
+		for j := 0; j < herm.length; j++ {
+			outRow += fmt.Sprint(herm.Get(i, j)) + " "
+		}
+		out += outRow + "\n"
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package vo2percolation
+
+import (
+	"math"
+	"math/cmplx"
+	"sort"
+	"testing"
+)
+
+func TestBuildHermitianMatrix(t *testing.T) {
+	herm := NewHermitianMatrix(5)
+	val := complex(1.0, 2.0)
+	herm.Set(3, 1, val)
+	if herm.Get(3, 1) != val || herm.Get(1, 3) != cmplx.Conj(val) {
+		t.Fatalf("unexpected value returned from herm.Get after herm.Set")
+	}
+}
+
+// Indices outside [0, length) are rejected.
+func TestHermitianMatrixBounds(t *testing.T) {
+	herm := NewHermitianMatrix(5)
+	for _, ij := range [][2]int{{5, 0}, {0, 5}, {-1, 0}, {0, -1}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Set(%d, %d) did not panic", ij[0], ij[1])
+				}
+			}()
+			herm.Set(ij[0], ij[1], 1.0)
+		}()
+	}
+}
+
+func TestHermitianEigensystem3x3WithZeros(t *testing.T) {
+	herm := NewHermitianMatrix(3)
+	herm.Set(0, 0, 2.0)
+	herm.Set(0, 2, 1i)
+	herm.Set(2, 2, 2.0)
+	vals, vs, err := herm.Eigensystem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Float64s(vals)
+	eps := 1e-12
+	if math.Abs(vals[0]-1.0) > eps || math.Abs(vals[1]-3.0) > eps {
+		t.Fatalf("incorrect eigenvalue returned")
+	}
+	// the empty row stays empty
+	for _, v := range vs[1] {
+		if v != 0 {
+			t.Fatalf("unexpected nonzero eigenvector")
+		}
+	}
+}
+
+// Return the sorted eigenvalues of every block of the Peierls Hamiltonian.
+func peierlsEnergies(t *testing.T, e *Energetics, g *Grid) []float64 {
+	energies := []float64{}
+	for _, H := range e.ElectronHamiltonianPeierls(g) {
+		vals, _, err := H.Eigensystem()
+		if err != nil {
+			t.Fatal(err)
+		}
+		energies = append(energies, vals...)
+	}
+	sort.Float64s(energies)
+	return energies
+}
+
+// Without a field, or with one flux quantum through every triangular
+// plaquette, the spectrum matches the real Hamiltonian.  Half a flux quantum
+// per plaquette changes it.
+func TestPeierlsFluxQuantum(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grid := activeGrid(3, 3)
+	expected, err := NewEnergetics(*env).ElectronEnergies(grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	triangleArea := RowSpacing / 2.0
+	differs := func(B float64) bool {
+		env.B = B
+		energies := peierlsEnergies(t, NewEnergetics(*env), grid)
+		for i := range energies {
+			if math.Abs(energies[i]-expected[i]) > 1e-9 {
+				return true
+			}
+		}
+		return false
+	}
+	if differs(0.0) || differs(1.0/triangleArea) {
+		t.Fatalf("Peierls spectrum differs from zero-field spectrum")
+	}
+	if !differs(0.5 / triangleArea) {
+		t.Fatalf("Peierls spectrum unaffected by half a flux quantum")
+	}
+}
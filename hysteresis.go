@@ -0,0 +1,121 @@
+// Temperature ramp protocols for thermal hysteresis loops.  A single grid is
+// heated from TMin to TMax and then cooled back to TMin, holding at each
+// temperature of the schedule for a fixed number of sweeps.  Temperatures
+// are in the same units as Delta (k_B = 1), so Beta = 1 / T.
+package vo2percolation
+
+import (
+	"fmt"
+	"math"
+)
+
+const RampScheduleError = "ramp schedule requires 0 < TMin < TMax, at least one step and holdSweeps > 0"
+const RampRateError = "ramp rate of %v sweeps per degree gives no sweeps at temperature steps of %v"
+
+// Temperatures visited on each branch of a hysteresis loop.
+type RampSchedule struct {
+	TMin, TMax float64
+	// Number of temperature intervals on each branch; the branch visits
+	// steps + 1 temperatures including both ends.
+	Steps int
+	// Sweeps spent at each temperature before it is recorded.
+	HoldSweeps int
+	// If true, space the temperatures evenly in Beta instead of T.
+	LinearInBeta bool
+}
+
+// State of the grid at the end of the hold at one temperature.
+type HysteresisPoint struct {
+	T, Beta            float64
+	MetallicFraction   float64 // active sites / (Lx * Ly)
+	LargestClusterSize int
+	Spanning           bool    // see Grid.Spans
+	Energy             float64 // AtomicHamiltonian
+}
+
+// Result of a ramp: the heating branch (increasing T) followed by the
+// cooling branch (decreasing T).  The cooling branch starts one step below
+// TMax, so that TMax is held and recorded only once, like every other
+// temperature; it has one point less than the heating branch.
+type HysteresisLoop struct {
+	Heating, Cooling []HysteresisPoint
+}
+
+// Create a (validated) schedule of steps + 1 temperatures per branch, each
+// held for holdSweeps sweeps.  With holdSweeps = 1 and many steps this is a
+// linear ramp in T; with few steps and many sweeps it is step-and-hold.
+func NewRampSchedule(TMin, TMax float64, steps, holdSweeps int, linearInBeta bool) (*RampSchedule, error) {
+	schedule := &RampSchedule{TMin, TMax, steps, holdSweeps, linearInBeta}
+	if !schedule.validate() {
+		return nil, fmt.Errorf(RampScheduleError)
+	}
+	return schedule, nil
+}
+
+// Create a (validated) schedule linear in T with temperature steps of at most
+// dT, ramping at sweepsPerDegree sweeps per unit of temperature.  The steps
+// are shrunk to divide TMax - TMin evenly, and the hold at each temperature
+// is the number of sweeps closest to sweepsPerDegree times the actual step.
+func NewRateRampSchedule(TMin, TMax, dT, sweepsPerDegree float64) (*RampSchedule, error) {
+	if dT <= 0 || sweepsPerDegree <= 0 || !(TMax > TMin) {
+		return nil, fmt.Errorf(RampScheduleError)
+	}
+	steps := int(math.Ceil((TMax - TMin) / dT))
+	step := (TMax - TMin) / float64(steps)
+	holdSweeps := int(math.Floor(sweepsPerDegree*step + 0.5))
+	if holdSweeps == 0 {
+		return nil, fmt.Errorf(RampRateError, sweepsPerDegree, step)
+	}
+	return NewRampSchedule(TMin, TMax, steps, holdSweeps, false)
+}
+
+// Do the fields of schedule have acceptable values?
+func (schedule *RampSchedule) validate() bool {
+	return schedule.TMin > 0 && schedule.TMax > schedule.TMin && schedule.Steps > 0 && schedule.HoldSweeps > 0
+}
+
+// Return the temperatures of the heating branch, from TMin to TMax.
+func (schedule *RampSchedule) Temperatures() []float64 {
+	Ts := make([]float64, schedule.Steps+1)
+	for i := range Ts {
+		frac := float64(i) / float64(schedule.Steps)
+		if schedule.LinearInBeta {
+			betaMax, betaMin := 1.0/schedule.TMin, 1.0/schedule.TMax
+			Ts[i] = 1.0 / (betaMax + frac*(betaMin-betaMax))
+		} else {
+			Ts[i] = schedule.TMin + frac*(schedule.TMax-schedule.TMin)
+		}
+	}
+	return Ts
+}
+
+// Heat grid along schedule and then cool it back, evolving it in place with
+// the sweeps of mc.  e gives the parameters other than Beta.  The cooling
+// branch revisits the temperatures of the heating branch below TMax.
+func (mc *MonteCarlo) Ramp(e *Energetics, grid *Grid, schedule *RampSchedule) (*HysteresisLoop, error) {
+	if !schedule.validate() {
+		return nil, fmt.Errorf(RampScheduleError)
+	}
+	heating := schedule.Temperatures()
+	cooling := make([]float64, len(heating)-1)
+	for i := range cooling {
+		cooling[i] = heating[len(heating)-2-i]
+	}
+	loop := new(HysteresisLoop)
+	runBranch := func(Ts []float64) []HysteresisPoint {
+		points := []HysteresisPoint{}
+		for _, T := range Ts {
+			eT := e.WithBeta(1.0 / T)
+			for s := 0; s < schedule.HoldSweeps; s++ {
+				mc.Sweep(eT, grid)
+			}
+			N := float64(grid.Lx() * grid.Ly())
+			point := HysteresisPoint{T, 1.0 / T, float64(grid.ActiveSiteCount()) / N, grid.LargestClusterSize(), grid.Spans(), e.AtomicHamiltonian(grid)}
+			points = append(points, point)
+		}
+		return points
+	}
+	loop.Heating = runBranch(heating)
+	loop.Cooling = runBranch(cooling)
+	return loop, nil
+}
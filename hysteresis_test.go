@@ -0,0 +1,79 @@
+package vo2percolation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRampScheduleTemperatures(t *testing.T) {
+	schedule, err := NewRampSchedule(1.0, 2.0, 4, 10, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []float64{1.0, 1.25, 1.5, 1.75, 2.0}
+	for i, T := range schedule.Temperatures() {
+		if math.Abs(T-expected[i]) > 1e-12 {
+			t.Fatalf("temperature %d is %v; expected %v", i, T, expected[i])
+		}
+	}
+	rate, err := NewRateRampSchedule(1.0, 2.0, 0.25, 40.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate.Steps != 4 || rate.HoldSweeps != 10 {
+		t.Fatalf("rate schedule has %d steps of %d sweeps; expected 4 of 10", rate.Steps, rate.HoldSweeps)
+	}
+	if _, err = NewRampSchedule(2.0, 1.0, 4, 10, false); err == nil {
+		t.Fatalf("accepted TMax < TMin")
+	}
+}
+
+// The rate schedule delivers the requested sweeps per degree even when dT
+// doesn't divide TMax - TMin.
+func TestRateRampScheduleSweepsPerDegree(t *testing.T) {
+	sweepsPerDegree := 40.0
+	schedule, err := NewRateRampSchedule(1.0, 2.0, 0.3, sweepsPerDegree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivered := float64(schedule.Steps*schedule.HoldSweeps) / (schedule.TMax - schedule.TMin)
+	if schedule.Steps != 4 || delivered != sweepsPerDegree {
+		t.Fatalf("schedule delivers %v sweeps per degree in %d steps; expected %v", delivered, schedule.Steps, sweepsPerDegree)
+	}
+	if _, err = NewRateRampSchedule(1.0, 2.0, 0.01, 10.0); err == nil {
+		t.Fatalf("accepted a rate with no sweeps per step")
+	}
+}
+
+// A dimerized grid heated far above Delta becomes partly metallic.
+func TestRampHeatsDimerizedGrid(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	mc, err := NewMonteCarlo(1e-12, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc.SetSeed(11)
+	schedule, err := NewRampSchedule(0.05, 20.0, 3, 20, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loop, err := mc.Ramp(e, DimerizedGrid(8, 8), schedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loop.Heating) != 4 || len(loop.Cooling) != 3 {
+		t.Fatalf("unexpected branch lengths %d and %d", len(loop.Heating), len(loop.Cooling))
+	}
+	cold, hot := loop.Heating[0], loop.Heating[3]
+	if cold.MetallicFraction != 0.0 || hot.MetallicFraction < 0.2 || hot.T != 20.0 {
+		t.Fatalf("unexpected metallic fractions %v (T = %v) and %v (T = %v)", cold.MetallicFraction, cold.T, hot.MetallicFraction, hot.T)
+	}
+	// TMax is held only on the heating branch
+	if loop.Cooling[0].T != loop.Heating[2].T || loop.Cooling[2].T != 0.05 {
+		t.Fatalf("cooling branch runs from %v to %v; expected %v to 0.05", loop.Cooling[0].T, loop.Cooling[2].T, loop.Heating[2].T)
+	}
+}
@@ -0,0 +1,114 @@
+// Named initial states for Monte Carlo runs.  Active sites are metallic;
+// inactive sites are dimerized.
+package vo2percolation
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+const InitialStateNameError = "unknown initial state %q"
+const InitialStateParameterError = "invalid parameter %v for initial state %q"
+
+// Names accepted by NamedInitialGrid.
+const (
+	DimerizedState  = "dimerized" // all sites inactive
+	MetallicState   = "metallic"  // all sites active
+	RandomState     = "random"    // each site active with probability param
+	StripeState     = "stripe"    // metallic stripe param rows wide
+	NucleusState    = "nucleus"   // metallic disk of radius param
+	FixedCountState = "fixed"     // param active sites at random positions
+)
+
+// Return the initial grid with dimensions (Lx, Ly) given by name, using rng
+// for random states.  param is
+// the active fraction for RandomState, the stripe width (in rows) for
+// StripeState and the nucleus radius (in units of the dimer-direction
+// spacing) for NucleusState and the number of active sites for
+// FixedCountState; it is ignored otherwise.
+func NamedInitialGrid(rng *rand.Rand, name string, Lx, Ly int, param float64) (*Grid, error) {
+	if Lx <= 0 || Ly <= 0 {
+		return nil, fmt.Errorf("invalid grid dimensions")
+	}
+	switch name {
+	case DimerizedState:
+		return DimerizedGrid(Lx, Ly), nil
+	case MetallicState:
+		return MetallicGrid(Lx, Ly), nil
+	case RandomState:
+		if param < 0 || param > 1 {
+			return nil, fmt.Errorf(InitialStateParameterError, param, name)
+		}
+		return RandomFractionGrid(rng, Lx, Ly, param), nil
+	case StripeState:
+		if param < 0 || param != math.Floor(param) {
+			return nil, fmt.Errorf(InitialStateParameterError, param, name)
+		}
+		return StripeGrid(Lx, Ly, int(param)), nil
+	case NucleusState:
+		if param < 0 {
+			return nil, fmt.Errorf(InitialStateParameterError, param, name)
+		}
+		return NucleusGrid(Lx, Ly, param), nil
+	case FixedCountState:
+		if param < 0 || param > float64(Lx*Ly) || param != math.Floor(param) {
+			return nil, fmt.Errorf(InitialStateParameterError, param, name)
+		}
+		return RandomConstrainedGrid(rng, Lx, Ly, int(param))
+	}
+	return nil, fmt.Errorf(InitialStateNameError, name)
+}
+
+// Return a grid with all sites dimerized (inactive).
+func DimerizedGrid(Lx, Ly int) *Grid {
+	return NewGridWithDims(Lx, Ly)
+}
+
+// Return a grid with all sites metallic (active).
+func MetallicGrid(Lx, Ly int) *Grid {
+	grid := NewGridWithDims(Lx, Ly)
+	grid.Iterate(func(p Point, value bool) {
+		grid.Set(p, true)
+	})
+	return grid
+}
+
+// Return a grid where each site is independently active with probability p,
+// using the generator rng.
+func RandomFractionGrid(rng *rand.Rand, Lx, Ly int, p float64) *Grid {
+	grid := NewGridWithDims(Lx, Ly)
+	grid.Iterate(func(pt Point, value bool) {
+		grid.Set(pt, RandomFloat(rng) < p)
+	})
+	return grid
+}
+
+// Return a dimerized grid with a metallic stripe of the given width (in
+// rows) running in the dimer (x) direction through the middle of the grid,
+// joining the left and right edges.
+func StripeGrid(Lx, Ly, width int) *Grid {
+	grid := NewGridWithDims(Lx, Ly)
+	yMin := (Ly - width) / 2
+	grid.Iterate(func(p Point, value bool) {
+		if p.Y() >= yMin && p.Y() < yMin+width {
+			grid.Set(p, true)
+		}
+	})
+	return grid
+}
+
+// Return a dimerized grid with a metallic nucleus: all sites within radius
+// of the center of the grid (measured with Grid.Position) are active.
+func NucleusGrid(Lx, Ly int, radius float64) *Grid {
+	grid := NewGridWithDims(Lx, Ly)
+	xc, yc := grid.Position(Point{Lx / 2, Ly / 2})
+	grid.Iterate(func(p Point, value bool) {
+		x, y := grid.Position(p)
+		// allow for rounding in the row spacing
+		if math.Hypot(x-xc, y-yc) <= radius+1e-9 {
+			grid.Set(p, true)
+		}
+	})
+	return grid
+}
@@ -0,0 +1,73 @@
+package vo2percolation
+
+import "testing"
+
+func TestNamedInitialGrids(t *testing.T) {
+	Lx, Ly := 8, 6
+	counts := map[string]int{DimerizedState: 0, MetallicState: Lx * Ly, StripeState: 2 * Lx}
+	for name, expected := range counts {
+		grid, err := NamedInitialGrid(NewRandom(1), name, Lx, Ly, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if grid.ActiveSiteCount() != expected {
+			t.Fatalf("%s grid has %d active sites; expected %d", name, grid.ActiveSiteCount(), expected)
+		}
+	}
+	// the stripe joins the left and right edges
+	stripe := StripeGrid(Lx, Ly, 1)
+	if cluster := stripe.LargestCluster(); cluster.Size() != Lx {
+		t.Fatalf("stripe is not a single cluster")
+	}
+	// a nucleus of radius 1 holds the center and its six neighbors
+	nucleus, err := NamedInitialGrid(NewRandom(1), NucleusState, Lx, Ly, 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nucleus.ActiveSiteCount() != 7 || !nucleus.Get(Point{Lx / 2, Ly / 2}) {
+		t.Fatalf("unexpected nucleus grid %v", nucleus)
+	}
+	fixed, err := NamedInitialGrid(NewRandom(1), FixedCountState, Lx, Ly, 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixed.ActiveSiteCount() != 11 {
+		t.Fatalf("fixed count grid has %d active sites; expected 11", fixed.ActiveSiteCount())
+	}
+	if _, err = NamedInitialGrid(NewRandom(1), RandomState, Lx, Ly, 1.5); err == nil {
+		t.Fatalf("accepted active fraction above 1")
+	}
+	if _, err = NamedInitialGrid(NewRandom(1), "checkerboard", Lx, Ly, 0); err == nil {
+		t.Fatalf("accepted unknown initial state")
+	}
+}
+
+// SimulateFrom starts from the given grid and leaves it in the final state.
+func TestSimulateFromGrid(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	mc, err := NewMonteCarlo(1e-12, 50, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grid := MetallicGrid(6, 6)
+	output, err := mc.SimulateFrom(e, grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output[0].ActiveSites != 36 {
+		t.Fatalf("first output has %d active sites; expected 36", output[0].ActiveSites)
+	}
+	// continuing the run picks up from the final state of the first run
+	final := grid.ActiveSiteCount()
+	output, err = mc.SimulateFrom(e, grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output[0].ActiveSites != final {
+		t.Fatalf("continued run starts with %d active sites; expected %d", output[0].ActiveSites, final)
+	}
+}
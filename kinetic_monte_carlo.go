@@ -0,0 +1,281 @@
+// Rejection-free kinetic Monte Carlo (the n-fold way of Bortz, Kalos and
+// Lebowitz, J. Comput. Phys. 17, 10 (1975)) for single-site flips.  The flip
+// energy of a site depends only on its local environment class: its own state
+// and the state of its DimerPartner (or the lack of a partner), since the
+// AtomicHamiltonian couples each site to its partner only.  Every site in a
+// class flips with the same rate, so an event is chosen by picking a class in
+// proportion to its total rate and then a uniformly random site in it.  Each
+// event advances the physical time by an exponentially distributed interval
+// with mean 1 / (total rate), so no proposals are wasted at low temperature.
+//
+// Rates follow the acceptance rules of MonteCarlo: a site with flip energy dE
+// flips at attemptRate * min(1, exp(-Beta dE)) under MetropolisRule and at
+// attemptRate / (1 + exp(Beta dE)) under GlauberRule.  Times are therefore in
+// the same units as MonteCarloOutput.Time with the same attempt rate.
+package vo2percolation
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+const KineticMonteCarloValidateError = "kinetic Monte Carlo requires a positive attempt rate and a known acceptance rule"
+const KineticScheduleError = "kinetic Monte Carlo requires duration >= 0 and interval > 0"
+
+// Partner states distinguished by the environment classes.
+const (
+	noPartner = iota
+	inactivePartner
+	activePartner
+	numPartnerStates
+)
+
+// Number of environment classes: (own state) x (partner state).
+const numKineticClasses = 2 * numPartnerStates
+
+// Parameters for a kinetic Monte Carlo run.
+type KineticMonteCarlo struct {
+	// Attempts per site per unit time.
+	attemptRate float64
+	// Rule giving the flip probability per attempt.
+	rule AcceptanceRule
+	seed int64
+	rng  *rand.Rand
+}
+
+// Sites of g grouped by environment class, with the flip rate of each class.
+type kineticState struct {
+	g                *Grid
+	convertTo1D      func(Point) int
+	convertFrom1D    func(int) Point
+	rates            [numKineticClasses]float64
+	members          [numKineticClasses][]int
+	class, positions []int // class of each site and its index in members
+}
+
+// Create a new (input-validated) KineticMonteCarlo with the given attempt
+// rate and acceptance rule.  The random number generator is seeded with
+// TimeSeed(); use SetSeed to choose the seed.
+func NewKineticMonteCarlo(attemptRate float64, rule AcceptanceRule) (*KineticMonteCarlo, error) {
+	kmc := new(KineticMonteCarlo)
+	kmc.attemptRate = attemptRate
+	kmc.rule = rule
+	kmc.SetSeed(TimeSeed())
+	if !kmc.validate() {
+		return nil, fmt.Errorf(KineticMonteCarloValidateError)
+	}
+	return kmc, nil
+}
+
+// Do the fields of kmc have acceptable values?
+func (kmc *KineticMonteCarlo) validate() bool {
+	rateOk := kmc.attemptRate > 0 && !math.IsInf(kmc.attemptRate, 1)
+	return rateOk && (kmc.rule == MetropolisRule || kmc.rule == GlauberRule)
+}
+
+// Restart the random number generator of kmc from seed.
+func (kmc *KineticMonteCarlo) SetSeed(seed int64) {
+	kmc.seed = seed
+	kmc.rng = NewRandom(seed)
+}
+
+// Return the seed last given to kmc.
+func (kmc *KineticMonteCarlo) Seed() int64 {
+	return kmc.seed
+}
+
+// Return the flip rate of a site with flip energy energyChange.
+func (kmc *KineticMonteCarlo) flipRate(e *Energetics, energyChange float64) float64 {
+	// avoid 0 * Inf at Beta = +Inf
+	logRatio := 0.0
+	if energyChange != 0 {
+		logRatio = e.LogBoltzmann(energyChange)
+	}
+	if kmc.rule == GlauberRule {
+		if logRatio >= 0 {
+			return kmc.attemptRate / (1.0 + math.Exp(-logRatio))
+		}
+		return kmc.attemptRate * math.Exp(logRatio) / (1.0 + math.Exp(logRatio))
+	}
+	return kmc.attemptRate * math.Min(1.0, math.Exp(logRatio))
+}
+
+// Evolve grid in place for the given duration of physical time, calling each
+// of observers at times 0, interval, 2 interval, ... up to duration.  The
+// sweep argument given to the observers is the index of the observation.
+// Returns the number of flips performed.  If no site can flip (for example
+// at Beta = +Inf) the grid is frozen and the remaining observations see the
+// same grid.
+func (kmc *KineticMonteCarlo) Run(e *Energetics, grid *Grid, duration, interval float64, observers ...Observer) (int, error) {
+	if !(duration >= 0) || !(interval > 0) {
+		return 0, fmt.Errorf(KineticScheduleError)
+	}
+	state := newKineticState(kmc, e, grid)
+	events, observation := 0, 0
+	var runErr error
+	// observe every pending time before the given one
+	observeUntil := func(t float64) {
+		for ; runErr == nil && float64(observation)*interval <= duration && float64(observation)*interval < t; observation++ {
+			for _, obs := range observers {
+				if runErr = obs.Observe(e, grid, observation, kmc.seed); runErr != nil {
+					break
+				}
+			}
+		}
+	}
+	time := 0.0
+	for runErr == nil {
+		total := state.totalRate()
+		if total <= 0 {
+			observeUntil(math.Inf(1))
+			break
+		}
+		// waiting time until the next flip; 1 - RandomFloat is in (0, 1]
+		dt := -math.Log(1.0-RandomFloat(kmc.rng)) / total
+		observeUntil(time + dt)
+		if runErr != nil || time+dt > duration {
+			break
+		}
+		time += dt
+		state.flip(state.chooseSite(RandomFloat(kmc.rng)*total, kmc.rng))
+		events++
+	}
+	for _, obs := range observers {
+		if err := obs.Finish(); err != nil && runErr == nil {
+			runErr = err
+		}
+	}
+	return events, runErr
+}
+
+// Evolve grid in place for the given duration, recording it at times 0,
+// interval, 2 interval, ... in the form of MonteCarlo.Simulate (without grid
+// snapshots).
+func (kmc *KineticMonteCarlo) SimulateFrom(e *Energetics, grid *Grid, duration, interval float64) ([]*MonteCarloOutput, error) {
+	outputList := []*MonteCarloOutput{}
+	collect := ObserverFunc(func(e *Energetics, grid *Grid, index int, seed int64) error {
+		thisOutput := new(MonteCarloOutput)
+		thisOutput.ActiveSites = grid.ActiveSiteCount()
+		thisOutput.Dimers = grid.DimerCount()
+		thisOutput.LargestClusterSize = grid.LargestClusterSize()
+		thisOutput.Time = float64(index) * interval
+		thisOutput.Energy = e.AtomicHamiltonian(grid)
+		thisOutput.Seed = seed
+		outputList = append(outputList, thisOutput)
+		return nil
+	})
+	if _, err := kmc.Run(e, grid, duration, interval, collect); err != nil {
+		return nil, err
+	}
+	return outputList, nil
+}
+
+// Classify every site of grid and compute the rate of each class.
+func newKineticState(kmc *KineticMonteCarlo, e *Energetics, grid *Grid) *kineticState {
+	state := new(kineticState)
+	state.g = grid
+	state.convertTo1D, state.convertFrom1D = grid.ConvertTo1D(), grid.ConvertFrom1D()
+	for class := 0; class < numKineticClasses; class++ {
+		state.rates[class] = kmc.flipRate(e, classFlipEnergy(e, class))
+	}
+	N := grid.Lx() * grid.Ly()
+	state.class, state.positions = make([]int, N), make([]int, N)
+	grid.Iterate(func(p Point, value bool) {
+		id := state.convertTo1D(p)
+		class := kineticClass(grid, p)
+		state.class[id] = class
+		state.positions[id] = len(state.members[class])
+		state.members[class] = append(state.members[class], id)
+	})
+	return state
+}
+
+// Return the environment class of p on g.
+func kineticClass(g *Grid, p Point) int {
+	partnerState := noPartner
+	if partner, err := g.DimerPartner(p); err == nil {
+		partnerState = inactivePartner
+		if g.Get(partner) {
+			partnerState = activePartner
+		}
+	}
+	if g.Get(p) {
+		return numPartnerStates + partnerState
+	}
+	return partnerState
+}
+
+// Return the flip energy (as in SiteFlipEnergy) of a site in class.
+func classFlipEnergy(e *Energetics, class int) float64 {
+	active, partnerState := class >= numPartnerStates, class%numPartnerStates
+	energyChange := e.Delta()
+	if active {
+		energyChange = -e.Delta()
+	}
+	if partnerState == activePartner {
+		// activating forms a dimer; deactivating breaks one
+		if active {
+			energyChange += e.V()
+		} else {
+			energyChange -= e.V()
+		}
+	}
+	return energyChange
+}
+
+// Return the sum of the flip rates of all sites.
+func (state *kineticState) totalRate() float64 {
+	total := 0.0
+	for class, members := range state.members {
+		total += state.rates[class] * float64(len(members))
+	}
+	return total
+}
+
+// Return the site whose flip is chosen by r in [0, totalRate()): the class
+// is chosen in proportion to its total rate, then a site uniformly within it.
+func (state *kineticState) chooseSite(r float64, rng *rand.Rand) Point {
+	last := -1
+	for class, members := range state.members {
+		if len(members) == 0 || state.rates[class] == 0 {
+			continue
+		}
+		last = class
+		classRate := state.rates[class] * float64(len(members))
+		if r < classRate {
+			break
+		}
+		r -= classRate
+	}
+	// rounding may leave r past the last class; use the last one
+	members := state.members[last]
+	return state.convertFrom1D(members[rng.Intn(len(members))])
+}
+
+// Flip p and update the classes of p and its partner.
+func (state *kineticState) flip(p Point) {
+	state.g.Toggle(p)
+	state.reclassify(p)
+	if partner, err := state.g.DimerPartner(p); err == nil {
+		state.reclassify(partner)
+	}
+}
+
+// Move p to the class of its current environment.
+func (state *kineticState) reclassify(p Point) {
+	id := state.convertTo1D(p)
+	oldClass, newClass := state.class[id], kineticClass(state.g, p)
+	if oldClass == newClass {
+		return
+	}
+	// remove from the old class by moving its last member into the gap
+	old := state.members[oldClass]
+	lastId := old[len(old)-1]
+	old[state.positions[id]] = lastId
+	state.positions[lastId] = state.positions[id]
+	state.members[oldClass] = old[:len(old)-1]
+	state.class[id] = newClass
+	state.positions[id] = len(state.members[newClass])
+	state.members[newClass] = append(state.members[newClass], id)
+}
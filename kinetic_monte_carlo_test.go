@@ -0,0 +1,105 @@
+package vo2percolation
+
+import (
+	"math"
+	"testing"
+)
+
+// The class flip energies agree with SiteFlipEnergy and the class lists stay
+// consistent with the grid as it evolves.
+func TestKineticClasses(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	kmc, err := NewKineticMonteCarlo(1.0, MetropolisRule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kmc.SetSeed(4)
+	// odd Lx leaves the last column without partners
+	grid := RandomFractionGrid(kmc.rng, 7, 4, 0.5)
+	state := newKineticState(kmc, e, grid)
+	for step := 0; step < 500; step++ {
+		for class, members := range state.members {
+			for pos, id := range members {
+				p := state.convertFrom1D(id)
+				if kineticClass(grid, p) != class || state.positions[id] != pos {
+					t.Fatalf("site %v misfiled in class %d", p, class)
+				}
+				if classFlipEnergy(e, class) != e.SiteFlipEnergy(grid, p) {
+					t.Fatalf("class %d flip energy %v differs from SiteFlipEnergy %v", class, classFlipEnergy(e, class), e.SiteFlipEnergy(grid, p))
+				}
+			}
+		}
+		state.flip(state.chooseSite(RandomFloat(kmc.rng)*state.totalRate(), kmc.rng))
+	}
+}
+
+// Without dimer coupling the active fraction relaxes as in
+// TestRelaxationRates, with the same rates per unit time.
+func TestKineticRelaxation(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.V = 0.0
+	e := NewEnergetics(*env)
+	up := math.Exp(-env.Beta * env.Delta)
+	nEq := up / (1.0 + up)
+	rates := map[AcceptanceRule]float64{GlauberRule: 1.0, MetropolisRule: 1.0 + up}
+	for rule, lambda := range rates {
+		kmc, err := NewKineticMonteCarlo(2.0, rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kmc.SetSeed(8)
+		output, err := kmc.SimulateFrom(e, MetallicGrid(60, 60), 2.0, 0.25)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(output) != 9 || output[8].Time != 2.0 {
+			t.Fatalf("rule %d: %d outputs; expected 9 up to time 2", rule, len(output))
+		}
+		for _, out := range output {
+			fraction := float64(out.ActiveSites) / 3600.0
+			expected := nEq + (1.0-nEq)*math.Exp(-lambda*2.0*out.Time)
+			if math.Abs(fraction-expected) > 0.03 {
+				t.Fatalf("rule %d at time %v: active fraction %v; expected %v", rule, out.Time, fraction, expected)
+			}
+		}
+	}
+}
+
+// Time averages over a long run match the exact thermal averages.
+func TestKineticEquilibrium(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.V = 2.0
+	e := NewEnergetics(*env)
+	Lx, Ly := 4, 2
+	exactActive, exactDimers := exactAtomicAverages(e, Lx, Ly)
+	kmc, err := NewKineticMonteCarlo(1.0, GlauberRule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kmc.SetSeed(10)
+	output, err := kmc.SimulateFrom(e, NewGridWithDims(Lx, Ly), 20000.0, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	active := mean(ObservableSeries(output, ActiveSitesObservable))
+	dimers := mean(ObservableSeries(output, DimersObservable))
+	if math.Abs(active-exactActive) > 0.05 || math.Abs(dimers-exactDimers) > 0.05 {
+		t.Fatalf("<Na> = %v, <Nd> = %v; expected %v, %v", active, dimers, exactActive, exactDimers)
+	}
+	// at Beta = +Inf from the ground state nothing can flip
+	frozen := e.WithBeta(math.Inf(1))
+	events, err := kmc.Run(frozen, NewGridWithDims(Lx, Ly), 5.0, 1.0)
+	if err != nil || events != 0 {
+		t.Fatalf("frozen grid had %d events (error %v)", events, err)
+	}
+}
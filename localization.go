@@ -0,0 +1,197 @@
+// Diagnostics for distinguishing extended from localized electron states on
+// the metallic network: the inverse participation ratio of each eigenstate
+// and the level-spacing ratio statistic <r>.  For uncorrelated (localized)
+// levels <r> ~ 0.386; for extended states with GOE statistics <r> ~ 0.531.
+package vo2percolation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const LevelSpacingWindowError = "level spacing statistics require eMax > eMin and at least one window"
+
+// Properties of a single electron eigenstate on a grid.
+type StateDiagnostics struct {
+	Energy float64
+	// Inverse participation ratio sum_i |psi_i|^4 for normalized psi:
+	// 1 for a state on a single site, ~1/N for a state spread over N sites.
+	IPR float64
+	// Index of the ElectronHamiltonian block the state belongs to.  Blocks
+	// are independent symmetry sectors.
+	Orbital int
+	// Index in e.ElectronClusters(g) of the cluster holding most of the
+	// state's weight, and the size of that cluster.
+	Cluster, ClusterSize int
+}
+
+// Return the diagnostics for every eigenstate of the electron Hamiltonian
+// on g.
+func (e *Energetics) StateDiagnostics(g *Grid) ([]StateDiagnostics, error) {
+	clusters := e.ElectronClusters(g)
+	convert := g.ConvertTo1D()
+	N := g.Lx() * g.Ly()
+	// cluster index of each site
+	siteCluster := make(map[int]int)
+	for c, cluster := range clusters {
+		for _, p := range cluster.Elements() {
+			siteCluster[convert(p)] = c
+		}
+	}
+	states := []StateDiagnostics{}
+	for orbital, H := range e.ElectronHamiltonian(g) {
+		evals, evecs, err := H.Eigensystem()
+		if err != nil {
+			return nil, err
+		}
+		evecs = nonzeroVectors(evecs)
+		for k, energy := range evals {
+			weights := make([]float64, len(clusters))
+			for i, val := range evecs[k] {
+				// row i belongs to the site with 1D index i % N
+				if c, ok := siteCluster[i%N]; ok {
+					weights[c] += val * val
+				}
+			}
+			tag := 0
+			for c, w := range weights {
+				if w > weights[tag] {
+					tag = c
+				}
+			}
+			state := StateDiagnostics{energy, IPR(evecs[k]), orbital, tag, 0}
+			if len(clusters) > 0 {
+				state.ClusterSize = clusters[tag].Size()
+			}
+			states = append(states, state)
+		}
+	}
+	return states, nil
+}
+
+// Inverse participation ratio of the (not necessarily normalized) state psi.
+func IPR(psi []float64) float64 {
+	sum2, sum4 := 0.0, 0.0
+	for _, val := range psi {
+		sq := val * val
+		sum2 += sq
+		sum4 += sq * sq
+	}
+	return sum4 / (sum2 * sum2)
+}
+
+// Eigensystem pads its eigenvectors with zero vectors for the empty rows of
+// the matrix; return only the nonzero vectors, which are in the same order as
+// the eigenvalues.
+func nonzeroVectors(vectors [][]float64) [][]float64 {
+	nonzero := [][]float64{}
+	for _, v := range vectors {
+		for _, val := range v {
+			if val != 0.0 {
+				nonzero = append(nonzero, v)
+				break
+			}
+		}
+	}
+	return nonzero
+}
+
+// Accumulates the level-spacing ratio r_n = min(s_n, s_n-1) / max(s_n, s_n-1)
+// in energy windows over many configurations.  Spacings are taken only
+// between levels of the same orbital on the same cluster, since levels on
+// disconnected clusters are uncorrelated by construction.
+type LevelSpacingStatistics struct {
+	eMin, eMax     float64
+	minClusterSize int // ignore clusters smaller than this
+	sums           []float64
+	counts         []int
+}
+
+// Create level spacing statistics with the given number of equal-width
+// energy windows covering [eMin, eMax).
+func NewLevelSpacingStatistics(eMin, eMax float64, windows, minClusterSize int) (*LevelSpacingStatistics, error) {
+	if eMax <= eMin || windows < 1 {
+		return nil, fmt.Errorf(LevelSpacingWindowError)
+	}
+	ls := new(LevelSpacingStatistics)
+	ls.eMin, ls.eMax = eMin, eMax
+	ls.minClusterSize = minClusterSize
+	ls.sums = make([]float64, windows)
+	ls.counts = make([]int, windows)
+	return ls, nil
+}
+
+// Add the levels of one configuration to the statistics.
+func (ls *LevelSpacingStatistics) Add(states []StateDiagnostics) {
+	// group levels by orbital and cluster
+	type key struct{ orbital, cluster int }
+	groups := make(map[key][]float64)
+	for _, s := range states {
+		if s.ClusterSize < ls.minClusterSize {
+			continue
+		}
+		k := key{s.Orbital, s.Cluster}
+		groups[k] = append(groups[k], s.Energy)
+	}
+	for _, levels := range groups {
+		sort.Float64s(levels)
+		for n := 1; n+1 < len(levels); n++ {
+			sPrev, sNext := levels[n]-levels[n-1], levels[n+1]-levels[n]
+			max := math.Max(sPrev, sNext)
+			if max == 0 {
+				continue
+			}
+			w := ls.window(levels[n])
+			if w < 0 {
+				continue
+			}
+			ls.sums[w] += math.Min(sPrev, sNext) / max
+			ls.counts[w]++
+		}
+	}
+}
+
+// Return the index of the window containing energy, or -1 if it lies outside
+// all windows.
+func (ls *LevelSpacingStatistics) window(energy float64) int {
+	if energy < ls.eMin || energy >= ls.eMax {
+		return -1
+	}
+	width := (ls.eMax - ls.eMin) / float64(len(ls.sums))
+	w := int((energy - ls.eMin) / width)
+	if w >= len(ls.sums) {
+		w = len(ls.sums) - 1
+	}
+	return w
+}
+
+// Return the center energy of each window.
+func (ls *LevelSpacingStatistics) WindowCenters() []float64 {
+	width := (ls.eMax - ls.eMin) / float64(len(ls.sums))
+	centers := make([]float64, len(ls.sums))
+	for w := range centers {
+		centers[w] = ls.eMin + (float64(w)+0.5)*width
+	}
+	return centers
+}
+
+// Return <r> in each window; windows without any spacings give NaN.
+func (ls *LevelSpacingStatistics) MeanRatio() []float64 {
+	means := make([]float64, len(ls.sums))
+	for w := range means {
+		if ls.counts[w] == 0 {
+			means[w] = math.NaN()
+		} else {
+			means[w] = ls.sums[w] / float64(ls.counts[w])
+		}
+	}
+	return means
+}
+
+// Return the number of spacing ratios collected in each window.
+func (ls *LevelSpacingStatistics) Counts() []int {
+	counts := make([]int, len(ls.counts))
+	copy(counts, ls.counts)
+	return counts
+}
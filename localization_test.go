@@ -0,0 +1,62 @@
+package vo2percolation
+
+import (
+	"math"
+	"testing"
+)
+
+// A dimer of two active sites has bonding and antibonding states spread
+// evenly over both sites; an isolated active site holds a state on one site.
+func TestStateDiagnosticsIPR(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	grid := NewGridWithDims(4, 1)
+	grid.Set(Point{0, 0}, true)
+	grid.Set(Point{1, 0}, true)
+	grid.Set(Point{3, 0}, true)
+	states, err := e.StateDiagnostics(grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != 6 {
+		t.Fatalf("expected one state per active site and orbital")
+	}
+	for _, s := range states {
+		expected := 0.5
+		if s.ClusterSize == 1 {
+			expected = 1.0
+		}
+		if math.Abs(s.IPR-expected) > 1e-12 {
+			t.Fatalf("unexpected IPR %v for state on cluster of size %d", s.IPR, s.ClusterSize)
+		}
+	}
+}
+
+// Equally spaced levels have r = 1; levels on different clusters are not
+// compared.
+func TestLevelSpacingStatistics(t *testing.T) {
+	ls, err := NewLevelSpacingStatistics(0.0, 10.0, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := []StateDiagnostics{}
+	for n := 0; n < 10; n++ {
+		states = append(states, StateDiagnostics{float64(n), 0.1, 0, 0, 10})
+		// a second cluster interleaves its levels with the first
+		states = append(states, StateDiagnostics{float64(n) + 0.1, 0.1, 0, 1, 10})
+		// levels on clusters that are too small are ignored
+		states = append(states, StateDiagnostics{float64(n) + 0.5, 1.0, 0, 2, 1})
+	}
+	ls.Add(states)
+	for w, r := range ls.MeanRatio() {
+		if math.Abs(r-1.0) > 1e-12 {
+			t.Fatalf("unexpected <r> = %v in window %d", r, w)
+		}
+	}
+	if counts := ls.Counts(); counts[0]+counts[1] != 16 {
+		t.Fatalf("unexpected number of spacing ratios")
+	}
+}
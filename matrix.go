@@ -109,20 +109,7 @@ func (sym *SymmetricMatrix) Equals(comp *SymmetricMatrix) bool {
 // The map returned converts from row indices in the returned matrix to row
 // indices in the original matrix.
 func (sym *SymmetricMatrix) RemoveEmptyRows() (*SymmetricMatrix, map[int]int) {
-	nonEmpty, convert := make([]bool, sym.length), make(map[int]int)
-	// Build a list of non-empty rows.
-	for i, row := range sym.data {
-		for j, val := range row {
-			if val != 0.0 {
-				if i != j {
-					nonEmpty[i] = true
-					nonEmpty[j] = true
-				} else {
-					nonEmpty[i] = true
-				}
-			}
-		}
-	}
+	nonEmpty, convert := sym.nonEmptyRows(), make(map[int]int)
 	// Count the number of non-empty rows and build the map from the old
 	// indexing to the indexing without empty rows.
 	iNew := 0
@@ -186,38 +173,41 @@ func InsertEmptyRows(reduced [][]float64, convert map[int]int, length int) [][]f
 }
 
 // Return an ordered slice of the eigenvalues of sym, and a slice of the
-// eigenvectors in the same order.
-func (sym *SymmetricMatrix) Eigensystem() ([]float64, [][]float64) {
-	originalSize := sym.length
-	reduced, convert := sym.RemoveEmptyRows()
-	size := C.size_t(reduced.length)
-	eigenvalues := C.gsl_vector_alloc(size)
-	eigenvectors := C.gsl_matrix_alloc(size, size)
-	matrix := reduced.toMatrix()
-	work := C.gsl_eigen_symmv_alloc(size)
-	err := C.gsl_eigen_symmv(matrix, eigenvalues, eigenvectors, work)
-	if err != 0 {
-		// handle it
+// eigenvectors in the same order.  Returns a GSLError if GSL fails, e.g. when
+// sym has no nonzero elements.
+func (sym *SymmetricMatrix) Eigensystem() ([]float64, [][]float64, error) {
+	return defaultEigenSolver.Eigensystem(sym)
+}
+
+// Return the eigenvalues of sym without computing the eigenvectors.  Errors
+// are returned as in Eigensystem.
+func (sym *SymmetricMatrix) Eigenvalues() ([]float64, error) {
+	return defaultEigenSolver.Eigenvalues(sym)
+}
+
+// Return true for each row of sym with a nonzero element.
+func (sym *SymmetricMatrix) nonEmptyRows() []bool {
+	nonEmpty := make([]bool, sym.length)
+	for i, row := range sym.data {
+		for j, val := range row {
+			if val != 0.0 {
+				nonEmpty[i] = true
+				nonEmpty[j] = true
+			}
+		}
 	}
-	goEigenvalues := vectorToSlice(eigenvalues)
-	goEigenvectors := matrixColumnsToSlices(eigenvectors)
-	C.gsl_vector_free(eigenvalues)
-	C.gsl_matrix_free(eigenvectors)
-	C.gsl_matrix_free(matrix)
-	C.gsl_eigen_symmv_free(work)
-	retEigenvectors := InsertEmptyRows(goEigenvectors, convert, originalSize)
-	return goEigenvalues, retEigenvectors
+	return nonEmpty
 }
 
-// Return the GSL matrix representation of sym.
-func (sym *SymmetricMatrix) toMatrix() *C.gsl_matrix {
-	// start with a zeroed matrix
-	size := C.size_t(sym.length)
-	matrix := C.gsl_matrix_calloc(size, size)
-	// iterate over sym, setting the corresponding elements in matrix
+// Write the elements of sym into the zeroed GSL matrix, moving the element
+// (i, j) to (newIndex[i], newIndex[j]).
+func (sym *SymmetricMatrix) setReduced(matrix *C.gsl_matrix, newIndex []int) {
 	for i, row := range sym.data {
 		for j, val := range row {
-			it, jt := C.size_t(i), C.size_t(j)
+			if val == 0.0 {
+				continue
+			}
+			it, jt := C.size_t(newIndex[i]), C.size_t(newIndex[j])
 			dval := C.double(val)
 			C.gsl_matrix_set(matrix, it, jt, dval)
 			if i != j {
@@ -225,7 +215,6 @@ func (sym *SymmetricMatrix) toMatrix() *C.gsl_matrix {
 			}
 		}
 	}
-	return matrix
 }
 
 func (sym *SymmetricMatrix) String() string {
@@ -248,16 +237,3 @@ func vectorToSlice(v *C.gsl_vector) []float64 {
 	}
 	return xs
 }
-
-func matrixColumnsToSlices(m *C.gsl_matrix) [][]float64 {
-	vectors := [][]float64{}
-	var i, j C.size_t
-	for i = 0; i < m.size1; i++ {
-		xs := []float64{}
-		for j = 0; j < m.size2; j++ {
-			xs = append(xs, float64(C.gsl_matrix_get(m, j, i)))
-		}
-		vectors = append(vectors, xs)
-	}
-	return vectors
-}
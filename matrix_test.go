@@ -64,7 +64,10 @@ func TestEigensystem2x2(t *testing.T) {
 	sym.Set(0, 0, 2.0)
 	sym.Set(1, 0, 1.0)
 	sym.Set(1, 1, 2.0)
-	vals, vs := sym.Eigensystem()
+	vals, vs, err := sym.Eigensystem()
+	if err != nil {
+		t.Fatal(err)
+	}
 	eps := 1e-12
 	neq := func(x float64, y float64) bool {
 		return math.Abs(x-y) > eps
@@ -84,7 +87,10 @@ func TestEigensystem3x3WithZeros(t *testing.T) {
 	sym.Set(0, 0, 2.0)
 	sym.Set(2, 0, 1.0)
 	sym.Set(2, 2, 2.0)
-	vals, vs := sym.Eigensystem()
+	vals, vs, err := sym.Eigensystem()
+	if err != nil {
+		t.Fatal(err)
+	}
 	eps := 1e-12
 	neq := func(x float64, y float64) bool {
 		return math.Abs(x-y) > eps
@@ -108,7 +114,10 @@ func TestEigensystem7x7Really3x3(t *testing.T) {
 	sym.Set(3, 3, 2.0)
 	sym.Set(3, 5, 1.0)
 	sym.Set(5, 5, 2.0)
-	vals, vs := sym.Eigensystem()
+	vals, vs, err := sym.Eigensystem()
+	if err != nil {
+		t.Fatal(err)
+	}
 	eps := 1e-12
 	neq := func(x float64, y float64) bool {
 		return math.Abs(x-y) > eps
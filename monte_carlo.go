@@ -4,33 +4,122 @@ package vo2percolation
 import (
 	"fmt"
 	"math"
+	"math/rand"
 )
 
 const MonteCarloValidateError = "Monte Carlo input parameters are invalid"
+const SiteOrderError = "unknown site order %d"
+const MoveTypeError = "unknown move type %d"
+const AcceptanceRuleError = "unknown acceptance rule %d"
+const AttemptRateError = "attempt rate must be positive"
+
+// Order in which a sweep visits the sites of the grid.
+type SiteOrder int
+
+const (
+	// Lx * Ly sites chosen uniformly at random (with repetition).
+	RandomOrder SiteOrder = iota
+	// Every site once, row by row (x fastest).
+	SequentialOrder
+	// Every site once, one sublattice at a time (see Grid.Sublattices).
+	// Sites on the same sublattice are not neighbors, so their flips are
+	// independent.
+	CheckerboardOrder
+)
+
+// Kind of update attempted at each visited site.
+type MoveType int
+
+const (
+	// Flip the site between active and inactive.
+	FlipMove MoveType = iota
+	// Exchange the site with a random neighbor (Kawasaki dynamics).  The
+	// number of active sites is conserved.
+	LocalKawasakiMove
+	// Exchange the site with a random site anywhere on the grid.  The number
+	// of active sites is conserved.
+	NonlocalKawasakiMove
+	// Flip the site and its DimerPartner together (only the site if it has
+	// no partner).
+	DimerPairMove
+	// Flip a cluster of sites in the same state, grown from the site (see
+	// SetClusterBondProbability).
+	ClusterMove
+	// Number of move types.
+	numMoveTypes
+)
+
+// Probability of accepting a proposed move with energy change dE.
+type AcceptanceRule int
+
+const (
+	// Accept with probability min(1, exp(-Beta dE)).  The random number is
+	// offset by etaMinimum.
+	MetropolisRule AcceptanceRule = iota
+	// Heat-bath (Glauber) rule: accept with probability
+	// 1 / (1 + exp(Beta dE)).
+	GlauberRule
+)
 
 // Hold parameters necesarry to perform the simulation
 type MonteCarlo struct {
 	// Minimum value of random flip acceptance probability (must be > 0).
 	etaMinimum float64
-	// How many total steps should be taken in the simulation?
-	totalSteps int
-	// Number of steps to take between snapshots of the grid.
-	// If recordInterval is <= 0, only snapshot the final grid.
+	// How many sweeps (Lx * Ly attempted flips) should be taken in the
+	// simulation?
+	totalSweeps int
+	// Number of sweeps to take between snapshots of the grid.
+	// If recordInterval is <= 0, don't take snapshots.
 	recordInterval int
+	// Order in which each sweep visits the sites.
+	siteOrder SiteOrder
+	// Relative probability of attempting each move type at a visited site.
+	moveWeights [numMoveTypes]float64
+	// Rule for accepting proposed moves.
+	acceptanceRule AcceptanceRule
+	// Attempts per site per unit of Monte Carlo time; a sweep lasts
+	// 1 / attemptRate.
+	attemptRate float64
+	// Probability of adding each same-state neighbor to a growing cluster.
+	clusterBondProbability float64
+	// Attempted and accepted moves of each type since the last
+	// ResetMoveStatistics.
+	moveAttempts, moveAccepts [numMoveTypes]int
+	// Number of active sites in the starting grid of Simulate; if < 0, use
+	// the number expected for non-interacting sites.
+	initialActive int
+	// Seed of rng, which supplies all random numbers used by mc.
+	seed int64
+	rng  *rand.Rand
 }
 
-// Data reported for each time step in the simulation
+// Data reported for each sweep in the simulation
 type MonteCarloOutput struct {
 	ActiveSites, Dimers, LargestClusterSize int
-	Grid                                    *Grid // may be nil
+	Time                                    float64 // sweeps / attemptRate
+	Energy                                  float64 // AtomicHamiltonian
+	Grid                                    *Grid   // may be nil
+	Conductance                             float64 // e^2/h; set only with Grid
+	Resistivity                             float64 // set only with Grid
+	Seed                                    int64   // seed of the MonteCarlo
 }
 
 // Create a new (input-validated) MonteCarlo with the given parameters.
-func NewMonteCarlo(etaMinimum float64, totalSteps, recordInterval int) (*MonteCarlo, error) {
+// totalSweeps and recordInterval are counted in sweeps.  Sites are visited
+// in RandomOrder.  The random number generator is seeded with TimeSeed();
+// use SetSeed to choose the seed.
+func NewMonteCarlo(etaMinimum float64, totalSweeps, recordInterval int) (*MonteCarlo, error) {
 	mc := new(MonteCarlo)
 	mc.etaMinimum = etaMinimum
-	mc.totalSteps = totalSteps
+	mc.totalSweeps = totalSweeps
 	mc.recordInterval = recordInterval
+	mc.siteOrder = RandomOrder
+	mc.moveWeights[FlipMove] = 1.0
+	mc.clusterBondProbability = defaultClusterBondProbability
+	mc.acceptanceRule = MetropolisRule
+	mc.attemptRate = 1.0
+	mc.initialActive = -1
+	mc.SetSeed(TimeSeed())
 	if !mc.validate() {
 		return nil, fmt.Errorf(MonteCarloValidateError)
 	}
@@ -39,7 +128,58 @@ func NewMonteCarlo(etaMinimum float64, totalSteps, recordInterval int) (*MonteCa
 
 // Do the fields of mc have acceptable values?
 func (mc *MonteCarlo) validate() bool {
-	return mc.etaMinimum > 0 && mc.totalSteps > 0
+	return mc.etaMinimum > 0 && mc.totalSweeps > 0
+}
+
+// Restart the random number generator of mc from seed.  A new MonteCarlo
+// with the same parameters and seed, making the same sequence of calls,
+// produces identical trajectories.
+func (mc *MonteCarlo) SetSeed(seed int64) {
+	mc.seed = seed
+	mc.rng = NewRandom(seed)
+}
+
+// Return the seed last given to mc.
+func (mc *MonteCarlo) Seed() int64 {
+	return mc.seed
+}
+
+// Set the order in which each sweep visits the sites.
+func (mc *MonteCarlo) SetSiteOrder(order SiteOrder) error {
+	if order != RandomOrder && order != SequentialOrder && order != CheckerboardOrder {
+		return fmt.Errorf(SiteOrderError, order)
+	}
+	mc.siteOrder = order
+	return nil
+}
+
+// Set the rule for accepting proposed moves.
+func (mc *MonteCarlo) SetAcceptanceRule(rule AcceptanceRule) error {
+	if rule != MetropolisRule && rule != GlauberRule {
+		return fmt.Errorf(AcceptanceRuleError, rule)
+	}
+	mc.acceptanceRule = rule
+	return nil
+}
+
+// Set the number of move attempts per site in one unit of Monte Carlo time
+// (1 by default), which gives MonteCarloOutput.Time.  With GlauberRule and
+// single-site flips, sites flip at the rates of Glauber dynamics in units of
+// 1 / rate, so relaxation times can be compared between runs and with
+// kinetic theory.
+func (mc *MonteCarlo) SetAttemptRate(rate float64) error {
+	if !(rate > 0) || math.IsInf(rate, 1) {
+		return fmt.Errorf(AttemptRateError)
+	}
+	mc.attemptRate = rate
+	return nil
+}
+
+// Start Simulate from a random grid with N active sites (see
+// RandomConstrainedGrid).  If N < 0, start from the number of active sites
+// expected for non-interacting sites (the default).
+func (mc *MonteCarlo) SetInitialActiveCount(N int) {
+	mc.initialActive = N
 }
 
 // Make a random perturbation on the Grid g.  If this perturbation leads to a
@@ -47,52 +187,172 @@ func (mc *MonteCarlo) validate() bool {
 // accept it with a random probability.  Return true if and only if the
 // perturbation is accepted.
 func (mc *MonteCarlo) Step(e *Energetics, g *Grid) bool {
-	// choose a random site
-	p := RandomPoint(g)
-	// calculate the energy change due to flipping (xf, yf)
-	energyChange := e.SiteFlipEnergy(g, p)
+	return mc.StepAt(e, g, RandomPoint(mc.rng, g))
+}
+
+// Attempt a move at the site p on g, accepting it as in Step.  The move type
+// is chosen at random with the weights of SetMoveWeights.  Return true if and
+// only if the move is accepted.
+func (mc *MonteCarlo) StepAt(e *Energetics, g *Grid, p Point) bool {
+	move := mc.chooseMove()
+	accepted := false
+	switch move {
+	case LocalKawasakiMove:
+		neighbors := g.Neighbors(p)
+		if len(neighbors) > 0 {
+			accepted = mc.swap(e, g, p, neighbors[mc.rng.Intn(len(neighbors))])
+		}
+	case NonlocalKawasakiMove:
+		accepted = mc.swap(e, g, p, RandomPoint(mc.rng, g))
+	case DimerPairMove:
+		accepted = mc.flipDimerPair(e, g, p)
+	case ClusterMove:
+		accepted = mc.flipCluster(e, g, p)
+	default:
+		// calculate the energy change due to flipping (xf, yf)
+		if mc.accept(e, e.SiteFlipEnergy(g, p)) {
+			g.Toggle(p)
+			accepted = true
+		}
+	}
+	mc.moveAttempts[move]++
+	if accepted {
+		mc.moveAccepts[move]++
+	}
+	return accepted
+}
+
+// Should a move with the given energy change be accepted?
+func (mc *MonteCarlo) accept(e *Energetics, energyChange float64) bool {
+	return mc.acceptLog(e.LogBoltzmann(energyChange))
+}
+
+// Should a move be accepted, given the log of the ratio of the probabilities
+// of the new and old states (including any ratio of proposal
+// probabilities)?
+func (mc *MonteCarlo) acceptLog(logRatio float64) bool {
+	if mc.acceptanceRule == GlauberRule {
+		// ratio / (1 + ratio), computed without overflow
+		var p float64
+		if logRatio >= 0 {
+			p = 1.0 / (1.0 + math.Exp(-logRatio))
+		} else {
+			p = math.Exp(logRatio) / (1.0 + math.Exp(logRatio))
+		}
+		return RandomFloat(mc.rng) < p
+	}
 	// going to lower energy: accept it
-	if energyChange < 0 {
-		g.Toggle(p)
+	if logRatio > 0 {
 		return true
 	}
 	// gaining energy: accept if eta + etaMinimum <= e^(-beta*energyChange)
-	log_eta := math.Log(RandomFloat() + mc.etaMinimum)
-	acceptFactor := e.LogBoltzmann(energyChange)
-	if log_eta <= acceptFactor {
-		g.Toggle(p)
-		return true
+	log_eta := math.Log(RandomFloat(mc.rng) + mc.etaMinimum)
+	return log_eta <= logRatio
+}
+
+// Attempt Lx * Ly moves on g, visiting sites in mc.siteOrder.  Return the
+// number of accepted moves.
+func (mc *MonteCarlo) Sweep(e *Energetics, g *Grid) int {
+	accepted := 0
+	visit := func(p Point) {
+		if mc.StepAt(e, g, p) {
+			accepted++
+		}
 	}
-	return false
+	switch mc.siteOrder {
+	case SequentialOrder:
+		for y := 0; y < g.Ly(); y++ {
+			for x := 0; x < g.Lx(); x++ {
+				visit(Point{x, y})
+			}
+		}
+	case CheckerboardOrder:
+		for _, sublattice := range g.Sublattices() {
+			for _, p := range sublattice {
+				visit(p)
+			}
+		}
+	default:
+		for i := 0; i < g.Lx()*g.Ly(); i++ {
+			visit(RandomPoint(mc.rng, g))
+		}
+	}
+	return accepted
 }
 
 // Run a simulation, starting from a random grid with dimensions (Lx, Ly) and
-// taking steps equal to mc.totalSteps.  Return a slice containg each recorded
+// taking sweeps equal to mc.totalSweeps.  Return a slice containg each recorded
 // grid.  May also want to return a slice of the times when each grid was
 // recorded.
 func (mc *MonteCarlo) Simulate(e *Energetics, Lx, Ly int) ([]*MonteCarloOutput, error) {
-	outputList := []*MonteCarloOutput{}
-	// estimate starting number of active sites
-	expectedActive := int(float64(Lx*Ly) * e.Boltzmann(e.Delta()))
-	// generate the initial grid
-	grid, err := RandomConstrainedGrid(Lx, Ly, expectedActive)
+	grid, err := mc.initialGrid(e, Lx, Ly)
 	if err != nil {
 		return nil, err
 	}
-	// Monte Carlo loop
-	for time := 0; time < mc.totalSteps; time++ {
-		thisOutput := new(MonteCarloOutput)
-		// log grid if it's the right time to
-		if mc.recordInterval > 0 && time%mc.recordInterval == 0 {
-			thisOutput.Grid = grid.Copy()
+	return mc.SimulateFrom(e, grid)
+}
+
+// Return a random grid with dimensions (Lx, Ly) with mc.initialActive active
+// sites, or by default roughly the number expected in equilibrium.
+func (mc *MonteCarlo) initialGrid(e *Energetics, Lx, Ly int) (*Grid, error) {
+	if mc.initialActive >= 0 {
+		return RandomConstrainedGrid(mc.rng, Lx, Ly, mc.initialActive)
+	}
+	// estimate starting number of active sites
+	expectedActive := int(float64(Lx*Ly) * e.Boltzmann(e.Delta()))
+	return RandomConstrainedGrid(mc.rng, Lx, Ly, expectedActive)
+}
+
+// Run a simulation starting from grid, taking sweeps equal to mc.totalSweeps.
+// grid is modified in place, so after SimulateFrom returns it holds the
+// final state and may be passed to SimulateFrom again to continue the run.
+func (mc *MonteCarlo) SimulateFrom(e *Energetics, grid *Grid) ([]*MonteCarloOutput, error) {
+	outputList := []*MonteCarloOutput{}
+	collect := ObserverFunc(func(e *Energetics, grid *Grid, sweep int, seed int64) error {
+		thisOutput, err := mc.record(e, grid, sweep)
+		if err != nil {
+			return err
 		}
-		// record the quantities we want to know for each configuration
-		thisOutput.ActiveSites = grid.ActiveSiteCount()
-		thisOutput.Dimers = grid.DimerCount()
-		thisOutput.LargestClusterSize = grid.LargestCluster().Size()
 		outputList = append(outputList, thisOutput)
-		// try to perturb the grid
-		mc.Step(e, grid) // could record failure/success here
+		return nil
+	})
+	// record every sweep; use Run with other observers to avoid keeping
+	// the whole history
+	if err := mc.Run(e, grid, Schedule{0, 1}, collect); err != nil {
+		return nil, err
 	}
 	return outputList, nil
 }
+
+// Return the output for grid at the given time (in sweeps).
+func (mc *MonteCarlo) record(e *Energetics, grid *Grid, time int) (*MonteCarloOutput, error) {
+	var err error
+	thisOutput := new(MonteCarloOutput)
+	// log grid if it's the right time to
+	if mc.recordInterval > 0 && time%mc.recordInterval == 0 {
+		thisOutput.Grid = grid.Copy()
+		thisOutput.Conductance, err = e.Conductance(grid)
+		if err != nil {
+			return nil, err
+		}
+		thisOutput.Resistivity, err = e.Resistivity(grid)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// record the quantities we want to know for each configuration
+	thisOutput.ActiveSites = grid.ActiveSiteCount()
+	thisOutput.Dimers = grid.DimerCount()
+	thisOutput.LargestClusterSize = grid.LargestClusterSize()
+	thisOutput.Energy = e.AtomicHamiltonian(grid)
+	thisOutput.Time = float64(time) / mc.attemptRate
+	thisOutput.Seed = mc.seed
+	return thisOutput, nil
+}
+
+// Return a copy of mc with its random number generator seeded with seed.
+func (mc *MonteCarlo) withSeed(seed int64) *MonteCarlo {
+	clone := *mc
+	clone.SetSeed(seed)
+	return &clone
+}
@@ -3,6 +3,8 @@ package vo2percolation
 import (
 	"flag"
 	"fmt"
+	"math"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -11,7 +13,7 @@ var mc_scaling *bool = flag.Bool("mc_scaling", false, "Run Monte Carlo scaling t
 
 // Want to know how the Monte Carlo simulation scales with grid size.
 // Procedure: use an LxL grid, with L steadily increasing. Run the simulation
-// for a constant number of steps. Record the time to execute mc.Simulate().
+// for a constant number of sweeps. Record the time to execute mc.Simulate().
 // Relevant outputs: L, execution time, execution time / L^2.
 // Only run if the command-line flag mcbench is present.
 func TestMonteCarloScaling(t *testing.T) {
@@ -22,7 +24,7 @@ func TestMonteCarloScaling(t *testing.T) {
 	}
 	// going to run the test; start setup
 	etaMinimum := 1e-12
-	totalSteps := 10000
+	totalSweeps := 10
 	recordInterval := 0
 	maxL := 64
 	env, err := EnvironmentFromFile("default.json")
@@ -30,7 +32,7 @@ func TestMonteCarloScaling(t *testing.T) {
 		t.Fatal(err)
 	}
 	energ := NewEnergetics(*env)
-	mc, err := NewMonteCarlo(etaMinimum, totalSteps, recordInterval)
+	mc, err := NewMonteCarlo(etaMinimum, totalSweeps, recordInterval)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -55,3 +57,183 @@ func TimeMonteCarloStep(energ *Energetics, mc *MonteCarlo, L int) (float64, erro
 	elapsedTime := time.Now().Sub(initTime).Seconds()
 	return elapsedTime, nil
 }
+
+// Starting from an all-metallic grid with the default parameters, every
+// first flip of a site lowers the energy.  Ordered sweeps visit each site
+// exactly once, so one sweep dimerizes the whole grid.
+func TestOrderedSweepVisitsEverySite(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	mc, err := NewMonteCarlo(1e-12, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, order := range []SiteOrder{SequentialOrder, CheckerboardOrder} {
+		if err = mc.SetSiteOrder(order); err != nil {
+			t.Fatal(err)
+		}
+		grid := MetallicGrid(7, 5)
+		accepted := mc.Sweep(e, grid)
+		if accepted != 35 || grid.ActiveSiteCount() != 0 {
+			t.Fatalf("order %d: accepted %d flips, leaving %d active sites", order, accepted, grid.ActiveSiteCount())
+		}
+	}
+	if err = mc.SetSiteOrder(SiteOrder(7)); err == nil {
+		t.Fatalf("accepted unknown site order")
+	}
+}
+
+// Two runs with the same seed produce the same trajectory.
+func TestSimulateReproducibleWithSeed(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	run := func(seed int64) []*MonteCarloOutput {
+		mc, err := NewMonteCarlo(1e-12, 20, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mc.SetSeed(seed)
+		output, err := mc.Simulate(e, 8, 8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return output
+	}
+	first, second := run(42), run(42)
+	for i := range first {
+		if first[i].Seed != 42 {
+			t.Fatalf("output records seed %d; expected 42", first[i].Seed)
+		}
+		if first[i].Grid == nil || second[i].Grid == nil {
+			t.Fatalf("grid not recorded at sweep %d", i)
+		}
+		if !reflect.DeepEqual(first[i].Grid.data, second[i].Grid.data) {
+			t.Fatalf("runs with the same seed differ at sweep %d", i)
+		}
+	}
+}
+
+// Kawasaki moves conserve the number of active sites.
+func TestKawasakiConservesActiveSites(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	mc, err := NewMonteCarlo(1e-12, 50, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc.SetSeed(3)
+	mc.SetInitialActiveCount(17)
+	for _, move := range []MoveType{LocalKawasakiMove, NonlocalKawasakiMove} {
+		if err = mc.SetMoveType(move); err != nil {
+			t.Fatal(err)
+		}
+		output, err := mc.Simulate(e, 8, 6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changed := false
+		for _, out := range output {
+			if out.ActiveSites != 17 {
+				t.Fatalf("move %d: %d active sites; expected 17", move, out.ActiveSites)
+			}
+			changed = changed || out.Dimers != output[0].Dimers
+		}
+		if !changed {
+			t.Fatalf("move %d never changed the grid", move)
+		}
+	}
+	if err = mc.SetMoveType(MoveType(7)); err == nil {
+		t.Fatalf("accepted unknown move type")
+	}
+}
+
+// Kawasaki moves sample the Boltzmann distribution at fixed active count.
+func TestKawasakiSamplesFixedCount(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.V = 2.0
+	e := NewEnergetics(*env)
+	Lx, Ly, N := 4, 2, 4
+	exactDimers := exactFixedCountDimers(e, Lx, Ly, N)
+	for _, move := range []MoveType{LocalKawasakiMove, NonlocalKawasakiMove} {
+		mc, err := NewMonteCarlo(1e-12, 20000, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mc.SetSeed(8)
+		mc.SetInitialActiveCount(N)
+		if err = mc.SetMoveType(move); err != nil {
+			t.Fatal(err)
+		}
+		output, err := mc.Simulate(e, Lx, Ly)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dimers := mean(ObservableSeries(output, DimersObservable))
+		if math.Abs(dimers-exactDimers) > 0.05 {
+			t.Fatalf("move %d: <Nd> = %v; expected %v", move, dimers, exactDimers)
+		}
+	}
+}
+
+// Without dimer coupling the sites relax independently after a quench from
+// the metallic state: the active fraction is n_eq + (1 - n_eq) exp(-lambda t)
+// with lambda = 1 for Glauber dynamics and 1 + exp(-Beta Delta) for
+// Metropolis, in units of the Monte Carlo time.
+func TestRelaxationRates(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.V = 0.0
+	e := NewEnergetics(*env)
+	up := math.Exp(-env.Beta * env.Delta)
+	nEq := up / (1.0 + up)
+	rates := map[AcceptanceRule]float64{GlauberRule: 1.0, MetropolisRule: 1.0 + up}
+	for rule, lambda := range rates {
+		mc, err := NewMonteCarlo(1e-12, 5, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mc.SetSeed(2)
+		if err = mc.SetAcceptanceRule(rule); err != nil {
+			t.Fatal(err)
+		}
+		if err = mc.SetAttemptRate(2.0); err != nil {
+			t.Fatal(err)
+		}
+		grid := MetallicGrid(60, 60)
+		output, err := mc.SimulateFrom(e, grid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, out := range output {
+			fraction := float64(out.ActiveSites) / 3600.0
+			expected := nEq + (1.0-nEq)*math.Exp(-lambda*2.0*out.Time)
+			if math.Abs(fraction-expected) > 0.03 {
+				t.Fatalf("rule %d at time %v: active fraction %v; expected %v", rule, out.Time, fraction, expected)
+			}
+		}
+		if output[3].Time != 1.5 {
+			t.Fatalf("sweep 3 recorded at time %v; expected 1.5", output[3].Time)
+		}
+	}
+	mc, err := NewMonteCarlo(1e-12, 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mc.SetAcceptanceRule(AcceptanceRule(5)) == nil || mc.SetAttemptRate(0.0) == nil {
+		t.Fatalf("accepted invalid acceptance rule or attempt rate")
+	}
+}
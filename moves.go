@@ -0,0 +1,213 @@
+// Proposal types for MonteCarlo beyond single-site flips, the mixing of move
+// types and per-type acceptance statistics.
+//
+// A cluster move grows a region of sites in the same state from a seed site:
+// each neighbor (see Grid.Neighbors) of a cluster site in the same state is
+// added with probability clusterBondProbability, each bond being tried once.
+// The whole cluster is then flipped.  The probability of growing the same
+// cluster after the flip differs only through the bonds from the cluster to
+// same-state sites outside it, all of which were refused; with n_out such
+// bonds before the flip and n_in after, the flip is accepted with
+// probability min(1, R) (or R / (1 + R) with GlauberRule), where
+// R = (1 - p)^(n_in - n_out) exp(-Beta dE).
+package vo2percolation
+
+import (
+	"fmt"
+	"math"
+)
+
+const MoveWeightsError = "move weights must be non-negative with a positive sum"
+const ClusterBondProbabilityError = "cluster bond probability must be in (0, 1)"
+
+// Default probability of adding a same-state neighbor to a cluster.
+const defaultClusterBondProbability = 0.5
+
+// Attempts and acceptances of one move type.
+type MoveStatistics struct {
+	Move               MoveType
+	Attempts, Accepted int
+}
+
+// Acceptance rate of the move (NaN without attempts).
+func (stats MoveStatistics) AcceptanceRate() float64 {
+	if stats.Attempts == 0 {
+		return math.NaN()
+	}
+	return float64(stats.Accepted) / float64(stats.Attempts)
+}
+
+// Attempt only moves of the given type at each visited site.  Kawasaki
+// moves conserve the number of active sites, so they are usually combined
+// with SetInitialActiveCount or a FixedCountState starting grid.
+func (mc *MonteCarlo) SetMoveType(move MoveType) error {
+	if move < 0 || move >= numMoveTypes {
+		return fmt.Errorf(MoveTypeError, move)
+	}
+	return mc.SetMoveWeights(map[MoveType]float64{move: 1.0})
+}
+
+// Attempt moves of each type in weights with probability proportional to
+// its weight; types not in weights are not attempted.
+func (mc *MonteCarlo) SetMoveWeights(weights map[MoveType]float64) error {
+	var moveWeights [numMoveTypes]float64
+	total := 0.0
+	for move, weight := range weights {
+		if move < 0 || move >= numMoveTypes {
+			return fmt.Errorf(MoveTypeError, move)
+		}
+		if weight < 0 || math.IsInf(weight, 1) {
+			return fmt.Errorf(MoveWeightsError)
+		}
+		moveWeights[move] = weight
+		total += weight
+	}
+	if total <= 0 {
+		return fmt.Errorf(MoveWeightsError)
+	}
+	for move := range moveWeights {
+		moveWeights[move] /= total
+	}
+	mc.moveWeights = moveWeights
+	return nil
+}
+
+// Set the probability of adding each same-state neighbor to a growing
+// cluster in ClusterMove.  Small values give small clusters.
+func (mc *MonteCarlo) SetClusterBondProbability(p float64) error {
+	if p <= 0 || p >= 1 {
+		return fmt.Errorf(ClusterBondProbabilityError)
+	}
+	mc.clusterBondProbability = p
+	return nil
+}
+
+// Return the attempts and acceptances of each move type attempted since mc
+// was created or ResetMoveStatistics was last called, in MoveType order.
+func (mc *MonteCarlo) MoveStatistics() []MoveStatistics {
+	stats := []MoveStatistics{}
+	for move := MoveType(0); move < numMoveTypes; move++ {
+		if mc.moveAttempts[move] > 0 {
+			stats = append(stats, MoveStatistics{move, mc.moveAttempts[move], mc.moveAccepts[move]})
+		}
+	}
+	return stats
+}
+
+// Clear the counts reported by MoveStatistics.
+func (mc *MonteCarlo) ResetMoveStatistics() {
+	mc.moveAttempts = [numMoveTypes]int{}
+	mc.moveAccepts = [numMoveTypes]int{}
+}
+
+// Return a move type chosen with the probabilities of mc.moveWeights.  No
+// random number is used if only one type has nonzero weight.
+func (mc *MonteCarlo) chooseMove() MoveType {
+	only, count := FlipMove, 0
+	for move, weight := range mc.moveWeights {
+		if weight > 0 {
+			only, count = MoveType(move), count+1
+		}
+	}
+	if count == 1 {
+		return only
+	}
+	r := RandomFloat(mc.rng)
+	for move, weight := range mc.moveWeights {
+		if r < weight {
+			return MoveType(move)
+		}
+		r -= weight
+	}
+	return only
+}
+
+// Attempt to exchange the states of p and q on g.  Exchanging two sites in
+// the same state changes nothing and counts as rejected.
+func (mc *MonteCarlo) swap(e *Energetics, g *Grid, p, q Point) bool {
+	if g.Get(p) == g.Get(q) {
+		return false
+	}
+	if mc.accept(e, e.SwapEnergy(g, p, q)) {
+		g.Toggle(p)
+		g.Toggle(q)
+		return true
+	}
+	return false
+}
+
+// Attempt to flip p and its dimer partner together.  A site without a
+// partner is flipped alone.
+func (mc *MonteCarlo) flipDimerPair(e *Energetics, g *Grid, p Point) bool {
+	sites := []Point{p}
+	if partner, err := g.DimerPartner(p); err == nil {
+		sites = append(sites, partner)
+	}
+	if mc.accept(e, flipEnergy(e, g, sites)) {
+		for _, q := range sites {
+			g.Toggle(q)
+		}
+		return true
+	}
+	return false
+}
+
+// Attempt to flip a cluster of sites in the same state as p, grown from p.
+func (mc *MonteCarlo) flipCluster(e *Energetics, g *Grid, p Point) bool {
+	state := g.Get(p)
+	bond := mc.clusterBondProbability
+	cluster := NewPointSet(g.ConvertFrom1D(), g.ConvertTo1D())
+	cluster.Add(p)
+	sites, queue := []Point{p}, []Point{p}
+	for len(queue) > 0 {
+		site := queue[0]
+		queue = queue[1:]
+		for _, q := range g.Neighbors(site) {
+			if g.Get(q) != state || cluster.Contains(q) {
+				continue
+			}
+			if RandomFloat(mc.rng) < bond {
+				cluster.Add(q)
+				sites = append(sites, q)
+				queue = append(queue, q)
+			}
+		}
+	}
+	// bonds to same-state sites outside the cluster, before (out) and
+	// after (in) the flip
+	out, in := 0, 0
+	for _, site := range sites {
+		for _, q := range g.Neighbors(site) {
+			if cluster.Contains(q) {
+				continue
+			}
+			if g.Get(q) == state {
+				out++
+			} else {
+				in++
+			}
+		}
+	}
+	logRatio := float64(in-out) * math.Log(1.0-bond)
+	if mc.acceptLog(e.LogBoltzmann(flipEnergy(e, g, sites)) + logRatio) {
+		for _, q := range sites {
+			g.Toggle(q)
+		}
+		return true
+	}
+	return false
+}
+
+// Return the energy change from flipping all of sites (which must be
+// distinct) on g.  g is left unchanged.
+func flipEnergy(e *Energetics, g *Grid, sites []Point) float64 {
+	energyChange := 0.0
+	for _, p := range sites {
+		energyChange += e.SiteFlipEnergy(g, p)
+		g.Toggle(p)
+	}
+	for _, p := range sites {
+		g.Toggle(p)
+	}
+	return energyChange
+}
@@ -0,0 +1,99 @@
+package vo2percolation
+
+import (
+	"math"
+	"testing"
+)
+
+// Return the exact thermal averages of the active site and dimer counts on
+// an Lx by Ly grid, by enumerating every grid.
+func exactAtomicAverages(e *Energetics, Lx, Ly int) (float64, float64) {
+	grid := NewGridWithDims(Lx, Ly)
+	Z, active, dimers := 0.0, 0.0, 0.0
+	for done := false; !done; done = grid.NextGrid() {
+		weight := e.Boltzmann(e.AtomicHamiltonian(grid))
+		Z += weight
+		active += weight * float64(grid.ActiveSiteCount())
+		dimers += weight * float64(grid.DimerCount())
+	}
+	return active / Z, dimers / Z
+}
+
+// Return the exact Boltzmann average of the number of dimers on an Lx by Ly
+// grid, over the grids with N active sites.
+func exactFixedCountDimers(e *Energetics, Lx, Ly, N int) float64 {
+	grid := NewGridWithDims(Lx, Ly)
+	Z, dimers := 0.0, 0.0
+	for done := false; !done; done = grid.NextGrid() {
+		if grid.ActiveSiteCount() != N {
+			continue
+		}
+		weight := e.Boltzmann(e.AtomicHamiltonian(grid))
+		Z += weight
+		dimers += weight * float64(grid.DimerCount())
+	}
+	return dimers / Z
+}
+
+// Mixed and cluster moves sample the Boltzmann distribution.
+func TestMovesSampleBoltzmann(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.V = 2.0
+	e := NewEnergetics(*env)
+	Lx, Ly := 4, 2
+	exactActive, exactDimers := exactAtomicAverages(e, Lx, Ly)
+	mixes := []map[MoveType]float64{
+		{ClusterMove: 1.0},
+		{FlipMove: 1.0, DimerPairMove: 1.0},
+		{FlipMove: 1.0, DimerPairMove: 2.0, ClusterMove: 1.0},
+	}
+	for _, weights := range mixes {
+		mc, err := NewMonteCarlo(1e-12, 20000, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mc.SetSeed(6)
+		if err = mc.SetMoveWeights(weights); err != nil {
+			t.Fatal(err)
+		}
+		output, err := mc.Simulate(e, Lx, Ly)
+		if err != nil {
+			t.Fatal(err)
+		}
+		active := mean(ObservableSeries(output, ActiveSitesObservable))
+		dimers := mean(ObservableSeries(output, DimersObservable))
+		if math.Abs(active-exactActive) > 0.05 || math.Abs(dimers-exactDimers) > 0.05 {
+			t.Fatalf("moves %v: <Na> = %v, <Nd> = %v; expected %v, %v", weights, active, dimers, exactActive, exactDimers)
+		}
+		stats := mc.MoveStatistics()
+		attempts := 0
+		for _, s := range stats {
+			if weights[s.Move] == 0 || s.Accepted > s.Attempts {
+				t.Fatalf("unexpected statistics %v for moves %v", s, weights)
+			}
+			attempts += s.Attempts
+		}
+		if len(stats) != len(weights) || attempts != 20000*Lx*Ly {
+			t.Fatalf("statistics %v do not cover every attempted move", stats)
+		}
+	}
+}
+
+func TestMoveWeightsValidation(t *testing.T) {
+	mc, err := NewMonteCarlo(1e-12, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid := []map[MoveType]float64{{}, {FlipMove: -1.0, ClusterMove: 2.0}, {MoveType(9): 1.0}}
+	for _, weights := range invalid {
+		if err = mc.SetMoveWeights(weights); err == nil {
+			t.Fatalf("accepted move weights %v", weights)
+		}
+	}
+	if err = mc.SetClusterBondProbability(1.0); err == nil {
+		t.Fatalf("accepted cluster bond probability 1")
+	}
+}
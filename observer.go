@@ -0,0 +1,184 @@
+// Observers measure the grid during a Monte Carlo run without keeping the
+// whole history in memory.  MonteCarlo.Run calls each observer at the sweeps
+// given by a Schedule; the built-in observers compute only the observables
+// they were asked for and stream each measurement to an io.Writer, so the
+// memory used by a run does not grow with its length.
+package vo2percolation
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const ScheduleError = "schedule requires Start >= 0 and Interval > 0"
+
+// Sweeps at which observers are called: Start, Start + Interval, ...
+type Schedule struct {
+	Start, Interval int
+}
+
+// Is sweep one of the sweeps of s?
+func (s Schedule) Due(sweep int) bool {
+	return sweep >= s.Start && (sweep-s.Start)%s.Interval == 0
+}
+
+// Do the fields of s have acceptable values?
+func (s Schedule) validate() bool {
+	return s.Start >= 0 && s.Interval > 0
+}
+
+// Receives the state of a Monte Carlo run.
+type Observer interface {
+	// Measure grid after sweep sweeps of the run with seed seed.  grid must
+	// not be modified or kept after Observe returns; use grid.Copy() to keep
+	// it.  Returning an error stops the run.
+	Observe(e *Energetics, grid *Grid, sweep int, seed int64) error
+	// Called once at the end of the run, including runs stopped by an error.
+	Finish() error
+}
+
+// Adapts a function to an Observer with nothing to do in Finish.
+type ObserverFunc func(e *Energetics, grid *Grid, sweep int, seed int64) error
+
+func (f ObserverFunc) Observe(e *Energetics, grid *Grid, sweep int, seed int64) error {
+	return f(e, grid, sweep, seed)
+}
+
+func (f ObserverFunc) Finish() error {
+	return nil
+}
+
+// Take mc.totalSweeps sweeps starting from grid, calling each of observers
+// before each sweep given by schedule.  grid is modified in place as in
+// SimulateFrom.  Returns the first error from an observer.
+func (mc *MonteCarlo) Run(e *Energetics, grid *Grid, schedule Schedule, observers ...Observer) error {
+	if !schedule.validate() {
+		return fmt.Errorf(ScheduleError)
+	}
+	var runErr error
+	for sweep := 0; sweep < mc.totalSweeps && runErr == nil; sweep++ {
+		if schedule.Due(sweep) {
+			for _, obs := range observers {
+				if runErr = obs.Observe(e, grid, sweep, mc.seed); runErr != nil {
+					break
+				}
+			}
+		}
+		if runErr == nil {
+			mc.Sweep(e, grid)
+		}
+	}
+	for _, obs := range observers {
+		if err := obs.Finish(); err != nil && runErr == nil {
+			runErr = err
+		}
+	}
+	return runErr
+}
+
+// Writes one line of tab-separated values per observation: the sweep
+// followed by the observables named in its header line.
+type SeriesWriter struct {
+	w     *bufio.Writer
+	names []string
+	// true once the header line has been written
+	started bool
+}
+
+// Create a SeriesWriter writing the observables names (see
+// MonteCarloObservables) to w.  Conductance and Resistivity are computed at
+// every observation if they are named.
+func NewSeriesWriter(w io.Writer, names []string) (*SeriesWriter, error) {
+	for _, name := range names {
+		if !isObservableName(name) {
+			return nil, fmt.Errorf(ObservableNameError, name)
+		}
+	}
+	sw := &SeriesWriter{bufio.NewWriter(w), make([]string, len(names)), false}
+	copy(sw.names, names)
+	return sw, nil
+}
+
+func (sw *SeriesWriter) Observe(e *Energetics, grid *Grid, sweep int, seed int64) error {
+	if !sw.started {
+		if _, err := fmt.Fprintf(sw.w, "Sweep\t%s\n", strings.Join(sw.names, "\t")); err != nil {
+			return err
+		}
+		sw.started = true
+	}
+	values := make([]string, len(sw.names))
+	for i, name := range sw.names {
+		val, err := MeasureObservable(e, grid, name)
+		if err != nil {
+			return err
+		}
+		values[i] = fmt.Sprint(val)
+	}
+	_, err := fmt.Fprintf(sw.w, "%d\t%s\n", sweep, strings.Join(values, "\t"))
+	return err
+}
+
+func (sw *SeriesWriter) Finish() error {
+	return sw.w.Flush()
+}
+
+// Writes one JSON object per observation holding the sweep, the seed and the
+// grid data, which can be passed to NewGrid.
+type SnapshotWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// A snapshot as written by SnapshotWriter.
+type Snapshot struct {
+	Sweep int
+	Seed  int64
+	Data  [][]bool // Data[x][y] is the state of site (x, y)
+}
+
+// Create a SnapshotWriter writing to w.
+func NewSnapshotWriter(w io.Writer) *SnapshotWriter {
+	buffered := bufio.NewWriter(w)
+	return &SnapshotWriter{buffered, json.NewEncoder(buffered)}
+}
+
+func (sw *SnapshotWriter) Observe(e *Energetics, grid *Grid, sweep int, seed int64) error {
+	return sw.enc.Encode(Snapshot{sweep, seed, grid.data})
+}
+
+func (sw *SnapshotWriter) Finish() error {
+	return sw.w.Flush()
+}
+
+// Return the value of the named observable (see MonteCarloObservables) on
+// grid.
+func MeasureObservable(e *Energetics, grid *Grid, name string) (float64, error) {
+	switch name {
+	case ActiveSitesObservable:
+		return float64(grid.ActiveSiteCount()), nil
+	case DimersObservable:
+		return float64(grid.DimerCount()), nil
+	case LargestClusterSizeObservable:
+		return float64(grid.LargestClusterSize()), nil
+	case EnergyObservable:
+		return e.AtomicHamiltonian(grid), nil
+	case ConductanceObservable:
+		return e.Conductance(grid)
+	case ResistivityObservable:
+		return e.Resistivity(grid)
+	}
+	return 0.0, fmt.Errorf(ObservableNameError, name)
+}
+
+// Is name one of MonteCarloObservables?
+func isObservableName(name string) bool {
+	for _, known := range MonteCarloObservables {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package vo2percolation
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRunStreamsObservations(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	mc, err := NewMonteCarlo(1e-12, 20, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc.SetSeed(5)
+	var series, snapshots bytes.Buffer
+	sw, err := NewSeriesWriter(&series, []string{ActiveSitesObservable, EnergyObservable})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// reference values taken directly from the grid
+	expected := []string{"Sweep\tActiveSites\tEnergy"}
+	reference := ObserverFunc(func(e *Energetics, grid *Grid, sweep int, seed int64) error {
+		expected = append(expected, fmt.Sprintf("%d\t%v\t%v", sweep, float64(grid.ActiveSiteCount()), e.AtomicHamiltonian(grid)))
+		return nil
+	})
+	grid, err := RandomConstrainedGrid(NewRandom(1), 6, 5, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mc.Run(e, grid, Schedule{2, 5}, sw, NewSnapshotWriter(&snapshots), reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(series.String()), "\n")
+	if len(lines) != 5 || strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected series output %q; expected %q", lines, expected)
+	}
+	scanner := bufio.NewScanner(&snapshots)
+	sweeps := []int{}
+	for scanner.Scan() {
+		var snap Snapshot
+		if err := json.Unmarshal(scanner.Bytes(), &snap); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewGrid(snap.Data); err != nil || snap.Seed != 5 {
+			t.Fatalf("invalid snapshot %v", snap)
+		}
+		sweeps = append(sweeps, snap.Sweep)
+	}
+	if fmt.Sprint(sweeps) != "[2 7 12 17]" {
+		t.Fatalf("snapshots taken at sweeps %v", sweeps)
+	}
+}
+
+// An observer error stops the run and is returned after every observer
+// finishes.
+func TestRunObserverError(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	mc, err := NewMonteCarlo(1e-12, 20, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	failing := ObserverFunc(func(e *Energetics, grid *Grid, sweep int, seed int64) error {
+		calls++
+		if sweep == 3 {
+			return fmt.Errorf("stop")
+		}
+		return nil
+	})
+	var series bytes.Buffer
+	sw, err := NewSeriesWriter(&series, []string{DimersObservable})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mc.Run(e, NewGridWithDims(4, 4), Schedule{0, 1}, sw, failing); err == nil || err.Error() != "stop" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if calls != 4 || strings.Count(series.String(), "\n") != 5 {
+		t.Fatalf("run did not stop at the failing observation")
+	}
+	if _, err := NewSeriesWriter(&series, []string{"Magnetization"}); err == nil {
+		t.Fatalf("accepted unknown observable")
+	}
+}
@@ -0,0 +1,162 @@
+// Parallel tempering (replica exchange) over a ladder of inverse
+// temperatures.  One grid is simulated at each Beta, with the replicas'
+// sweeps running in separate goroutines.  Every swapInterval sweeps, swaps
+// of the grids at neighboring temperatures are attempted and accepted with
+// probability min(1, exp((beta_k - beta_k+1) * (E_k - E_k+1))), where E is
+// the AtomicHamiltonian energy.
+package vo2percolation
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+const ParallelTemperingValidateError = "parallel tempering requires at least two positive Beta values and swapInterval > 0"
+const ParallelTemperingGridsError = "parallel tempering requires one grid per Beta value"
+
+// Parameters for a replica exchange run.
+type ParallelTempering struct {
+	// Template for the simulation at each temperature; gives the sweep
+	// count, recording interval, site order and seed.
+	mc *MonteCarlo
+	// Inverse temperatures, one per replica.
+	betas []float64
+	// Number of sweeps between rounds of swap attempts.
+	swapInterval int
+}
+
+// Result of a replica exchange run.
+type ParallelTemperingOutput struct {
+	Betas []float64
+	// Series[k] holds the output of each sweep at Betas[k], in the same form
+	// as MonteCarlo.Simulate.  Swaps move grids between temperatures, so a
+	// series follows a temperature rather than a single grid.
+	Series [][]*MonteCarloOutput
+	// Swap attempts and accepted swaps between Betas[k] and Betas[k+1].
+	SwapAttempts, SwapAccepts []int
+	// Final grid at each Beta.
+	Grids []*Grid
+}
+
+// Create a new (input-validated) ParallelTempering running a copy of mc at
+// each of betas.  Replica k uses the random stream DeriveSeed(mc.Seed(), k).
+func NewParallelTempering(mc *MonteCarlo, betas []float64, swapInterval int) (*ParallelTempering, error) {
+	pt := new(ParallelTempering)
+	pt.mc = mc
+	pt.betas = make([]float64, len(betas))
+	copy(pt.betas, betas)
+	pt.swapInterval = swapInterval
+	if !pt.validate() {
+		return nil, fmt.Errorf(ParallelTemperingValidateError)
+	}
+	return pt, nil
+}
+
+// Do the fields of pt have acceptable values?
+func (pt *ParallelTempering) validate() bool {
+	if len(pt.betas) < 2 || pt.swapInterval <= 0 {
+		return false
+	}
+	for _, beta := range pt.betas {
+		if beta <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Return the fraction of accepted swaps between each pair of neighboring
+// temperatures (NaN for pairs without attempts).
+func (out *ParallelTemperingOutput) SwapAcceptanceRates() []float64 {
+	rates := make([]float64, len(out.SwapAttempts))
+	for k := range rates {
+		if out.SwapAttempts[k] == 0 {
+			rates[k] = math.NaN()
+		} else {
+			rates[k] = float64(out.SwapAccepts[k]) / float64(out.SwapAttempts[k])
+		}
+	}
+	return rates
+}
+
+// Run replica exchange starting from random grids with dimensions (Lx, Ly).
+// e gives the parameters other than Beta.
+func (pt *ParallelTempering) Simulate(e *Energetics, Lx, Ly int) (*ParallelTemperingOutput, error) {
+	grids := make([]*Grid, len(pt.betas))
+	for k, beta := range pt.betas {
+		var err error
+		replica := pt.mc.withSeed(DeriveSeed(pt.mc.Seed(), len(pt.betas)+1+k))
+		grids[k], err = replica.initialGrid(e.WithBeta(beta), Lx, Ly)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pt.SimulateFrom(e, grids)
+}
+
+// Run replica exchange starting from grids[k] at Beta k.  The grids are
+// modified in place and exchanged between temperatures; the final grid at
+// each Beta is given in the output.
+func (pt *ParallelTempering) SimulateFrom(e *Energetics, grids []*Grid) (*ParallelTemperingOutput, error) {
+	M := len(pt.betas)
+	if len(grids) != M {
+		return nil, fmt.Errorf(ParallelTemperingGridsError)
+	}
+	out := new(ParallelTemperingOutput)
+	out.Betas = make([]float64, M)
+	copy(out.Betas, pt.betas)
+	out.Series = make([][]*MonteCarloOutput, M)
+	out.SwapAttempts, out.SwapAccepts = make([]int, M-1), make([]int, M-1)
+	out.Grids = make([]*Grid, M)
+	copy(out.Grids, grids)
+	replicas := make([]*MonteCarlo, M)
+	energetics := make([]*Energetics, M)
+	for k, beta := range pt.betas {
+		replicas[k] = pt.mc.withSeed(DeriveSeed(pt.mc.Seed(), k))
+		energetics[k] = e.WithBeta(beta)
+	}
+	swapRng := NewRandom(DeriveSeed(pt.mc.Seed(), M))
+	errs := make([]error, M)
+	for time := 0; time < pt.mc.totalSweeps; time += pt.swapInterval {
+		sweeps := pt.swapInterval
+		if time+sweeps > pt.mc.totalSweeps {
+			sweeps = pt.mc.totalSweeps - time
+		}
+		// run each replica until the next round of swaps
+		var wg sync.WaitGroup
+		for k := 0; k < M; k++ {
+			wg.Add(1)
+			go func(k int) {
+				defer wg.Done()
+				for s := 0; s < sweeps; s++ {
+					thisOutput, err := replicas[k].record(energetics[k], out.Grids[k], time+s)
+					if err != nil {
+						errs[k] = err
+						return
+					}
+					out.Series[k] = append(out.Series[k], thisOutput)
+					replicas[k].Sweep(energetics[k], out.Grids[k])
+				}
+			}(k)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			if err != nil {
+				return nil, err
+			}
+		}
+		// attempt to swap the grids at neighboring temperatures
+		for k := 0; k+1 < M; k++ {
+			out.SwapAttempts[k]++
+			Ek := e.AtomicHamiltonian(out.Grids[k])
+			Enext := e.AtomicHamiltonian(out.Grids[k+1])
+			logAccept := (pt.betas[k] - pt.betas[k+1]) * (Ek - Enext)
+			if logAccept >= 0 || math.Log(RandomFloat(swapRng)) < logAccept {
+				out.Grids[k], out.Grids[k+1] = out.Grids[k+1], out.Grids[k]
+				out.SwapAccepts[k]++
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,63 @@
+package vo2percolation
+
+import "testing"
+
+func TestParallelTemperingOutput(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	mc, err := NewMonteCarlo(1e-12, 25, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc.SetSeed(7)
+	betas := []float64{0.5, 1.0, 2.0}
+	pt, err := NewParallelTempering(mc, betas, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := pt.Simulate(e, 6, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := range betas {
+		if len(out.Series[k]) != 25 {
+			t.Fatalf("temperature %d has %d outputs; expected 25", k, len(out.Series[k]))
+		}
+	}
+	for k, rate := range out.SwapAcceptanceRates() {
+		// 25 sweeps in rounds of 4 gives 7 rounds of swaps
+		if out.SwapAttempts[k] != 7 || rate < 0 || rate > 1 {
+			t.Fatalf("unexpected swap statistics: %d attempts, rate %v", out.SwapAttempts[k], rate)
+		}
+	}
+}
+
+// Replicas at equal temperatures always swap.
+func TestParallelTemperingEqualBetas(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	mc, err := NewMonteCarlo(1e-12, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, err := NewParallelTempering(mc, []float64{1.0, 1.0}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := pt.Simulate(e, 4, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.SwapAccepts[0] != 10 {
+		t.Fatalf("accepted %d of 10 swaps between equal temperatures", out.SwapAccepts[0])
+	}
+	if _, err = NewParallelTempering(mc, []float64{1.0}, 1); err == nil {
+		t.Fatalf("accepted a single temperature")
+	}
+}
@@ -1,4 +1,6 @@
-// Functions for producing random values.
+// Functions for producing random values.  Each simulation carries its own
+// generator, created from an explicit seed with NewRandom, so that runs can
+// be reproduced and goroutines don't share a generator.
 package vo2percolation
 
 import (
@@ -6,21 +8,33 @@ import (
 	"time"
 )
 
-// To avoid calling rand.NewSource many times, r is a module-level variable.
-// This strategy is unlikely to scale over multiple goroutines!
-var r *rand.Rand = newRandom()
+// Create a new random value generator with the given seed.
+func NewRandom(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
+
+// Return a seed based on the current time, for runs which don't need a
+// particular seed.  The seed should be recorded so the run can be repeated.
+func TimeSeed() int64 {
+	return time.Now().UnixNano()
+}
 
-// Create a new random value generator.
-func newRandom() *rand.Rand {
-	src := rand.NewSource(time.Now().UnixNano())
-	return rand.New(src)
+// Return the seed of independent stream number stream derived from seed.
+// Derived seeds are scrambled with the SplitMix64 finalizer so that nearby
+// (seed, stream) pairs give unrelated generators.
+func DeriveSeed(seed int64, stream int) int64 {
+	z := uint64(seed) + uint64(stream+1)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z = z ^ (z >> 31)
+	return int64(z)
 }
 
 // Return a random boolean value.
 // --- does this produce a sufficiently random sample? ---
-func RandomBool() bool {
+func RandomBool(rng *rand.Rand) bool {
 	// pick 0 or 1 randomly
-	i := r.Intn(2)
+	i := rng.Intn(2)
 	// sanity check
 	if i < 0 || i > 1 {
 		panic("random integer outside expected bounds")
@@ -32,15 +46,14 @@ func RandomBool() bool {
 	return true
 }
 
-// Return a pair of random integer values whose maxima are given by topX, topY.
-func RandomPoint(g *Grid) Point {
-	// one seed for both rand.Intn calls
-	randX := r.Intn(g.Lx())
-	randY := r.Intn(g.Ly())
+// Return a random point in g.
+func RandomPoint(rng *rand.Rand, g *Grid) Point {
+	randX := rng.Intn(g.Lx())
+	randY := rng.Intn(g.Ly())
 	return Point{randX, randY}
 }
 
 // Return a random float64 in the range [0.0, 1.0).
-func RandomFloat() float64 {
-	return r.Float64()
+func RandomFloat(rng *rand.Rand) float64 {
+	return rng.Float64()
 }
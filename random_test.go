@@ -11,9 +11,10 @@ import (
 func TestRandomBoolIsRandom(t *testing.T) {
 	epsilon := 2e-2 // greatest allowed relError
 	repeatCount := int(math.Pow(2.0, 16.0))
+	rng := NewRandom(TimeSeed())
 	trueCount, falseCount := 0, 0
 	for i := 0; i < repeatCount; i++ {
-		val := RandomBool()
+		val := RandomBool(rng)
 		if val {
 			trueCount += 1
 		} else {
@@ -26,3 +27,19 @@ func TestRandomBoolIsRandom(t *testing.T) {
 		t.Fatalf("RandomBool() produced large excess of true or false")
 	}
 }
+
+// Derived streams differ from each other and from the parent seed.
+func TestDeriveSeed(t *testing.T) {
+	seed := int64(12345)
+	seen := map[int64]bool{seed: true}
+	for stream := 0; stream < 100; stream++ {
+		derived := DeriveSeed(seed, stream)
+		if seen[derived] {
+			t.Fatalf("stream %d repeats an earlier seed", stream)
+		}
+		seen[derived] = true
+		if DeriveSeed(seed, stream) != derived {
+			t.Fatalf("DeriveSeed is not deterministic")
+		}
+	}
+}
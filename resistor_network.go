@@ -0,0 +1,98 @@
+// Classical random resistor network built from a Grid.  Every pair of
+// neighbors (dimer and diagonal directions) is joined by a resistor.  Bonds
+// between two active (metallic) sites have resistance R_metal; bonds which
+// touch an inactive (dimerized) site have resistance R_dimer.  The left edge
+// (x = 0) is held at unit voltage and the right edge (x = Lx - 1) is grounded.
+package vo2percolation
+
+import "fmt"
+
+const NetworkParameterError = "resistor network requires R_metal > 0 and R_dimer > 0"
+const NetworkWidthError = "resistor network requires Lx >= 2"
+
+// Relative residual at which the Kirchhoff solution is accepted.
+const networkTolerance = 1e-10
+
+// Conductance between the left and right edges of g.
+func (e *Energetics) NetworkConductance(g *Grid) (float64, error) {
+	if e.env.R_metal <= 0 || e.env.R_dimer <= 0 {
+		return 0.0, fmt.Errorf(NetworkParameterError)
+	}
+	Lx, Ly := g.Lx(), g.Ly()
+	if Lx < 2 {
+		return 0.0, fmt.Errorf(NetworkWidthError)
+	}
+	convert := g.ConvertTo1D()
+	// bond conductance between the sites p and n
+	bond := func(p, n Point) float64 {
+		if g.Get(p) && g.Get(n) {
+			return 1.0 / e.env.R_metal
+		}
+		return 1.0 / e.env.R_dimer
+	}
+	// Unknown voltages live on the interior columns 0 < x < Lx - 1.
+	// Label them in the order given by ConvertTo1D on the interior.
+	interior := func(p Point) bool {
+		return p.X() > 0 && p.X() < Lx-1
+	}
+	unknown := make(map[int]int)
+	g.Iterate(func(p Point, value bool) {
+		if interior(p) {
+			unknown[convert(p)] = len(unknown)
+		}
+	})
+	n := len(unknown)
+	// Kirchhoff matrix restricted to the unknowns
+	builder, b := NewSparseBuilder(n), make([]float64, n)
+	g.Iterate(func(p Point, value bool) {
+		if !interior(p) {
+			return
+		}
+		i := unknown[convert(p)]
+		for _, nb := range g.Neighbors(p) {
+			c := bond(p, nb)
+			builder.Add(i, i, c)
+			if interior(nb) {
+				// each bond is visited from both ends
+				builder.Add(i, unknown[convert(nb)], -c/2)
+			} else if nb.X() == 0 {
+				// left edge is held at unit voltage
+				b[i] += c
+			}
+		}
+	})
+	A := builder.Build()
+	voltage := make([]float64, n)
+	if n > 0 {
+		if _, err := ConjugateGradient(A.MatVec, A.Diagonal(), b, voltage, networkTolerance, 10*n); err != nil {
+			return 0.0, err
+		}
+	}
+	// current leaving the left edge
+	current := 0.0
+	for y := 0; y < Ly; y++ {
+		p := Point{0, y}
+		for _, nb := range g.Neighbors(p) {
+			if nb.X() == 0 {
+				continue
+			}
+			v := 0.0
+			if interior(nb) {
+				v = voltage[unknown[convert(nb)]]
+			}
+			current += bond(p, nb) * (1.0 - v)
+		}
+	}
+	return current, nil
+}
+
+// Effective resistivity of g: the edge-to-edge resistance scaled by the
+// aspect ratio Ly / (Lx - 1), in the same units as R_metal.  A fully metallic
+// grid has resistivity of order R_metal.
+func (e *Energetics) Resistivity(g *Grid) (float64, error) {
+	G, err := e.NetworkConductance(g)
+	if err != nil {
+		return 0.0, err
+	}
+	return float64(g.Ly()) / (G * float64(g.Lx()-1)), nil
+}
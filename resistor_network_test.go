@@ -0,0 +1,46 @@
+package vo2percolation
+
+import (
+	"math"
+	"testing"
+)
+
+// Two metallic sites in the dimer direction are joined by a single resistor.
+// Adding a third site puts two resistors in series.
+func TestNetworkConductanceChain(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	for L := 2; L <= 5; L++ {
+		G, err := e.NetworkConductance(activeGrid(L, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := 1.0 / (env.R_metal * float64(L-1))
+		if math.Abs(G-expected) > 1e-9 {
+			t.Fatalf("unexpected conductance %v for chain of length %d", G, L)
+		}
+	}
+}
+
+// Uniform networks differ only by the bond resistance.
+func TestResistivityUniform(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	metal, err := e.Resistivity(activeGrid(6, 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dimer, err := e.Resistivity(NewGridWithDims(6, 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(dimer/metal-env.R_dimer/env.R_metal) > 1e-6 {
+		t.Fatalf("resistivity ratio does not match resistance ratio")
+	}
+}
@@ -1,88 +1,239 @@
-// Interface to GSL one-dimensional function solver.
+// One-dimensional root finding: Brent's method, bisection and a safeguarded
+// Newton iteration, each working on a bracketing interval.  ExpandBracket
+// finds such an interval starting from a guess.
 package vo2percolation
 
-/*
-#cgo LDFLAGS: -lgsl
-#include <gsl/gsl_errno.h>
-#include <gsl/gsl_math.h>
-#include <gsl/gsl_roots.h>
+import (
+	"fmt"
+	"math"
+)
 
-#define SOLVE1D_MAX_ITER 1024
+const RootBracketError = "left and right do not bracket a root"
+const RootMaxIterError = "failed to find root to desired accuracy in %d iterations"
+const RootExpandError = "failed to bracket a root after %d expansions"
 
-extern double goEvaluateSolve1D(double, void*);
-extern void goPackDataSolve1D(void*, double, int);
+// Iteration limit used by Solve1D.
+const DefaultRootMaxIter = 1024
 
-static double solve1D(double left, double right, double epsAbs, double epsRel, void* userdata) {
-	int status, iter = 0;
-	int converged = 1;
-	const gsl_root_fsolver_type *T;
-	gsl_root_fsolver *s;
-	double r = (left + right) / 2.0;
-	double x_lo = left, x_hi = right;
-	gsl_function F;
+// Convergence criteria for the root finders.  A root is accepted when the
+// bracket [left, right] satisfies
+// |right - left| < EpsAbs + EpsRel * min(|left|, |right|)
+// (taking the minimum to be 0 if the bracket contains 0).
+type RootOptions struct {
+	EpsAbs, EpsRel float64
+	MaxIter        int
+}
 
-	F.function = &goEvaluateSolve1D;
-	F.params = userdata;
-	T = gsl_root_fsolver_brent;
-	s = gsl_root_fsolver_alloc(T);
-	gsl_root_fsolver_set(s, &F, x_lo, x_hi);
+// Information about a root finding run.
+type RootDiagnostics struct {
+	// Number of iterations (function evaluations after the initial ones).
+	Iterations int
+	// Final bracket.
+	Left, Right float64
+}
 
-	do {
-		iter++;
-		status = gsl_root_fsolver_iterate(s);
-		r = gsl_root_fsolver_root(s);
-		x_lo = gsl_root_fsolver_x_lower(s);
-		x_hi = gsl_root_fsolver_x_upper(s);
-		status = gsl_root_test_interval(x_lo, x_hi, epsAbs, epsRel);
-	} while (status == GSL_CONTINUE && iter < SOLVE1D_MAX_ITER);
+// Find the root of error bracketed by left and right to absolute precision
+// epsAbs and relative precision epsRel using Brent's method.
+func Solve1D(error func(float64) float64, left, right, epsAbs, epsRel float64) (float64, error) {
+	root, _, err := Brent(error, left, right, RootOptions{epsAbs, epsRel, DefaultRootMaxIter})
+	return root, err
+}
 
-	gsl_root_fsolver_free(s);
-	if (iter >= SOLVE1D_MAX_ITER || status != GSL_SUCCESS) {
-		converged = 0;
+// Has the bracket [a, b] converged under opts?
+func (opts RootOptions) converged(a, b float64) bool {
+	lo, hi := math.Min(a, b), math.Max(a, b)
+	minAbs := math.Min(math.Abs(lo), math.Abs(hi))
+	if lo <= 0 && hi >= 0 {
+		minAbs = 0
 	}
-	goPackDataSolve1D(userdata, r, converged);
-	return r;
+	return hi-lo < opts.EpsAbs+opts.EpsRel*minAbs
 }
-*/
-import "C"
 
-import (
-	"fmt"
-	"unsafe"
-)
+// Do fa and fb have opposite signs (or is either 0)?
+func brackets(fa, fb float64) bool {
+	return (fa <= 0 && fb >= 0) || (fa >= 0 && fb <= 0)
+}
 
-type dataSolve1D struct {
-	f         func(float64) float64
-	root      float64
-	converged int
+// Find the root of f bracketed by left and right by bisection.
+func Bisection(f func(float64) float64, left, right float64, opts RootOptions) (float64, RootDiagnostics, error) {
+	a, b := left, right
+	fa, fb := f(a), f(b)
+	diag := RootDiagnostics{0, a, b}
+	if !brackets(fa, fb) {
+		return 0.0, diag, fmt.Errorf(RootBracketError)
+	}
+	if fa == 0 {
+		return a, diag, nil
+	}
+	if fb == 0 {
+		return b, diag, nil
+	}
+	for diag.Iterations < opts.MaxIter {
+		diag.Iterations++
+		mid := (a + b) / 2
+		fmid := f(mid)
+		if fmid == 0 {
+			diag.Left, diag.Right = mid, mid
+			return mid, diag, nil
+		}
+		if brackets(fa, fmid) {
+			b, fb = mid, fmid
+		} else {
+			a, fa = mid, fmid
+		}
+		diag.Left, diag.Right = a, b
+		if opts.converged(a, b) {
+			return (a + b) / 2, diag, nil
+		}
+	}
+	return 0.0, diag, fmt.Errorf(RootMaxIterError, opts.MaxIter)
 }
 
-//export goEvaluateSolve1D
-func goEvaluateSolve1D(x C.double, dataPtr unsafe.Pointer) C.double {
-	data := (*dataSolve1D)(dataPtr)
-	val := data.f(float64(x))
-	return C.double(val)
+// Find the root of f bracketed by left and right with Brent's method
+// (inverse quadratic interpolation and secant steps, falling back to
+// bisection when they converge slowly).
+func Brent(f func(float64) float64, left, right float64, opts RootOptions) (float64, RootDiagnostics, error) {
+	a, b := left, right
+	fa, fb := f(a), f(b)
+	diag := RootDiagnostics{0, a, b}
+	if !brackets(fa, fb) {
+		return 0.0, diag, fmt.Errorf(RootBracketError)
+	}
+	if fa == 0 {
+		return a, diag, nil
+	}
+	if fb == 0 {
+		return b, diag, nil
+	}
+	// b is the best estimate, and the root lies between b and c.
+	c, fc := a, fa
+	d := b - a
+	e := d
+	for diag.Iterations < opts.MaxIter {
+		diag.Iterations++
+		if math.Abs(fc) < math.Abs(fb) {
+			a, b, c = b, c, b
+			fa, fb, fc = fb, fc, fb
+		}
+		diag.Left, diag.Right = math.Min(b, c), math.Max(b, c)
+		tol := (opts.EpsAbs + opts.EpsRel*math.Abs(b)) / 2
+		m := (c - b) / 2
+		if fb == 0 || opts.converged(b, c) {
+			return b, diag, nil
+		}
+		if math.Abs(e) < tol || math.Abs(fa) <= math.Abs(fb) {
+			// bisect
+			d, e = m, m
+		} else {
+			var p, q float64
+			s := fb / fa
+			if a == c {
+				// secant
+				p = 2 * m * s
+				q = 1 - s
+			} else {
+				// inverse quadratic interpolation
+				q = fa / fc
+				r := fb / fc
+				p = s * (2*m*q*(q-r) - (b-a)*(r-1))
+				q = (q - 1) * (r - 1) * (s - 1)
+			}
+			if p > 0 {
+				q = -q
+			} else {
+				p = -p
+			}
+			if 2*p < math.Min(3*m*q-math.Abs(tol*q), math.Abs(e*q)) {
+				e, d = d, p/q
+			} else {
+				d, e = m, m
+			}
+		}
+		a, fa = b, fb
+		if math.Abs(d) > tol {
+			b += d
+		} else if m > 0 {
+			b += tol
+		} else {
+			b -= tol
+		}
+		fb = f(b)
+		if !brackets(fb, fc) {
+			c, fc = a, fa
+			d = b - a
+			e = d
+		}
+	}
+	return 0.0, diag, fmt.Errorf(RootMaxIterError, opts.MaxIter)
 }
 
-//export goPackDataSolve1D
-func goPackDataSolve1D(dataPtr unsafe.Pointer, root C.double, converged C.int) {
-	data := (*dataSolve1D)(dataPtr)
-	data.root = float64(root)
-	data.converged = int(converged)
+// Find the root of f bracketed by left and right with Newton's method, where
+// df is the derivative of f.  Newton steps which leave the bracket or fail to
+// halve |f| are replaced by bisection steps.
+func SafeguardedNewton(f, df func(float64) float64, left, right float64, opts RootOptions) (float64, RootDiagnostics, error) {
+	a, b := left, right
+	fa, fb := f(a), f(b)
+	diag := RootDiagnostics{0, a, b}
+	if !brackets(fa, fb) {
+		return 0.0, diag, fmt.Errorf(RootBracketError)
+	}
+	if fa == 0 {
+		return a, diag, nil
+	}
+	if fb == 0 {
+		return b, diag, nil
+	}
+	x := (a + b) / 2
+	fPrev := math.Inf(1)
+	for diag.Iterations < opts.MaxIter {
+		diag.Iterations++
+		fx := f(x)
+		if fx == 0 {
+			diag.Left, diag.Right = x, x
+			return x, diag, nil
+		}
+		// shrink the bracket
+		if brackets(fa, fx) {
+			b, fb = x, fx
+		} else {
+			a, fa = x, fx
+		}
+		diag.Left, diag.Right = math.Min(a, b), math.Max(a, b)
+		if opts.converged(a, b) {
+			return x, diag, nil
+		}
+		next := (a + b) / 2
+		if dfx := df(x); dfx != 0 && math.Abs(fx) <= math.Abs(fPrev)/2 {
+			newton := x - fx/dfx
+			if newton > math.Min(a, b) && newton < math.Max(a, b) {
+				next = newton
+				// a Newton step smaller than the tolerance has converged
+				if opts.converged(x, newton) {
+					diag.Left, diag.Right = math.Min(x, newton), math.Max(x, newton)
+					return newton, diag, nil
+				}
+			}
+		}
+		fPrev = fx
+		x = next
+	}
+	return 0.0, diag, fmt.Errorf(RootMaxIterError, opts.MaxIter)
 }
 
-// Find the root of error bracketed by left and right to absolute precision
-// epsAbs and relative precision epsRel.
-func Solve1D(error func(float64) float64, left, right, epsAbs, epsRel float64) (float64, error) {
-	errLeft, errRight := error(left), error(right)
-	if (errLeft > 0 && errRight > 0) || (errLeft < 0 && errRight < 0) {
-		return 0.0, fmt.Errorf("left and right do not bracket a root")
+// Expand [left, right] away from its center by factor until f changes sign
+// across it, at most maxExpand times.  Return the bracketing interval.
+func ExpandBracket(f func(float64) float64, left, right, factor float64, maxExpand int) (float64, float64, error) {
+	fLeft, fRight := f(left), f(right)
+	for i := 0; i < maxExpand; i++ {
+		if brackets(fLeft, fRight) {
+			return left, right, nil
+		}
+		center, half := (left+right)/2, (right-left)/2*factor
+		left, right = center-half, center+half
+		fLeft, fRight = f(left), f(right)
 	}
-	data := &dataSolve1D{error, 0.0, 0}
-	dataPtr := unsafe.Pointer(data)
-	C.solve1D(C.double(left), C.double(right), C.double(epsAbs), C.double(epsRel), dataPtr)
-	if data.converged == 0 {
-		return 0.0, fmt.Errorf("failed to find root to desired accuracy")
+	if brackets(fLeft, fRight) {
+		return left, right, nil
 	}
-	return data.root, nil
+	return 0.0, 0.0, fmt.Errorf(RootExpandError, maxExpand)
 }
@@ -23,3 +23,67 @@ func TestSolve1DLinear(t *testing.T) {
 		t.Fatalf("unexpected value for root")
 	}
 }
+
+func TestRootFindersCubic(t *testing.T) {
+	cubic := func(x float64) float64 {
+		return x*x*x - 2.0
+	}
+	dCubic := func(x float64) float64 {
+		return 3.0 * x * x
+	}
+	expectedRoot := math.Cbrt(2.0)
+	opts := RootOptions{1e-12, 1e-12, 200}
+	brent, brentDiag, err := Brent(cubic, 0.0, 10.0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bisect, bisectDiag, err := Bisection(cubic, 0.0, 10.0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newton, newtonDiag, err := SafeguardedNewton(cubic, dCubic, 0.0, 10.0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, root := range []float64{brent, bisect, newton} {
+		if math.Abs(root-expectedRoot) > 1e-11 {
+			t.Fatalf("unexpected value %v for root", root)
+		}
+	}
+	// the interpolating methods beat bisection
+	if brentDiag.Iterations >= bisectDiag.Iterations || newtonDiag.Iterations >= bisectDiag.Iterations {
+		t.Fatalf("unexpected iteration counts: Brent %d, Newton %d, bisection %d", brentDiag.Iterations, newtonDiag.Iterations, bisectDiag.Iterations)
+	}
+	if brentDiag.Left > expectedRoot || brentDiag.Right < expectedRoot {
+		t.Fatalf("final bracket [%v, %v] does not contain root", brentDiag.Left, brentDiag.Right)
+	}
+}
+
+func TestRootIterationLimit(t *testing.T) {
+	linear := func(x float64) float64 {
+		return x - 0.3
+	}
+	_, diag, err := Bisection(linear, 0.0, 1.0, RootOptions{1e-15, 0.0, 5})
+	if err == nil || diag.Iterations != 5 {
+		t.Fatalf("expected failure after 5 iterations; got %d iterations, error %v", diag.Iterations, err)
+	}
+}
+
+func TestExpandBracket(t *testing.T) {
+	linear := func(x float64) float64 {
+		return x - 1000.0
+	}
+	left, right, err := ExpandBracket(linear, -1.0, 1.0, 2.0, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left > 1000.0 || right < 1000.0 {
+		t.Fatalf("bracket [%v, %v] does not contain root", left, right)
+	}
+	constant := func(x float64) float64 {
+		return 1.0
+	}
+	if _, _, err = ExpandBracket(constant, -1.0, 1.0, 2.0, 8); err == nil {
+		t.Fatalf("expected failure to bracket a function without a root")
+	}
+}
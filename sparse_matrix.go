@@ -0,0 +1,239 @@
+// Symmetric matrix in compressed sparse row (CSR) form.  Matrices are built
+// by accumulating (row, column, value) triplets in a SparseBuilder, then
+// compressed once; the compressed matrix is read-only.  Both triangles are
+// stored so that each row can be traversed directly in MatVec.
+package vo2percolation
+
+/*
+#cgo LDFLAGS: -lgsl -lgslcblas
+#include <gsl/gsl_math.h>
+#include <gsl/gsl_vector.h>
+#include <gsl/gsl_matrix.h>
+#include <gsl/gsl_eigen.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Interface for accumulating the elements of a real symmetric matrix.
+// Add(i, j, x) adds x to the element (i, j) and to the element (j, i).
+// Implemented by SymmetricMatrix and SparseBuilder.
+type SymmetricBuilder interface {
+	Add(i, j int, x float64)
+}
+
+// Triplet (COO) builder for a SparseSymmetricMatrix.  Only the upper triangle
+// is recorded; repeated elements are summed by Build.
+type SparseBuilder struct {
+	length int
+	rows   []int
+	cols   []int
+	vals   []float64
+}
+
+type SparseSymmetricMatrix struct {
+	// dimensions (symmetric, so lx = ly)
+	length int
+	// The nonzero elements of row i are vals[rowStart[i]:rowStart[i+1]], in
+	// the columns cols[rowStart[i]:rowStart[i+1]] (in increasing order).
+	rowStart []int
+	cols     []int
+	vals     []float64
+}
+
+// Return a builder for an LxL symmetric matrix.
+func NewSparseBuilder(L int) *SparseBuilder {
+	builder := new(SparseBuilder)
+	builder.length = L
+	return builder
+}
+
+// Add x to the value at row i, column j (and row j, column i).
+func (builder *SparseBuilder) Add(i, j int, x float64) {
+	if i >= builder.length || j >= builder.length || i < 0 || j < 0 {
+		panic("matrix access out of bounds")
+	}
+	if i > j {
+		i, j = j, i
+	}
+	builder.rows = append(builder.rows, i)
+	builder.cols = append(builder.cols, j)
+	builder.vals = append(builder.vals, x)
+}
+
+// Sorts triplets by row, then column.
+type tripletSorter struct {
+	rows, cols []int
+	vals       []float64
+}
+
+func (ts tripletSorter) Len() int {
+	return len(ts.rows)
+}
+
+func (ts tripletSorter) Less(a, b int) bool {
+	if ts.rows[a] != ts.rows[b] {
+		return ts.rows[a] < ts.rows[b]
+	}
+	return ts.cols[a] < ts.cols[b]
+}
+
+func (ts tripletSorter) Swap(a, b int) {
+	ts.rows[a], ts.rows[b] = ts.rows[b], ts.rows[a]
+	ts.cols[a], ts.cols[b] = ts.cols[b], ts.cols[a]
+	ts.vals[a], ts.vals[b] = ts.vals[b], ts.vals[a]
+}
+
+// Compress the accumulated triplets into a SparseSymmetricMatrix.  Elements
+// which sum to zero are dropped.  The builder may be used again afterward.
+func (builder *SparseBuilder) Build() *SparseSymmetricMatrix {
+	// expand to both triangles
+	ts := tripletSorter{}
+	for k := range builder.rows {
+		i, j, x := builder.rows[k], builder.cols[k], builder.vals[k]
+		ts.rows = append(ts.rows, i)
+		ts.cols = append(ts.cols, j)
+		ts.vals = append(ts.vals, x)
+		if i != j {
+			ts.rows = append(ts.rows, j)
+			ts.cols = append(ts.cols, i)
+			ts.vals = append(ts.vals, x)
+		}
+	}
+	sort.Sort(ts)
+	sparse := new(SparseSymmetricMatrix)
+	sparse.length = builder.length
+	sparse.rowStart = make([]int, builder.length+1)
+	for k := 0; k < ts.Len(); {
+		// sum duplicates of (i, j)
+		i, j, sum := ts.rows[k], ts.cols[k], 0.0
+		for ; k < ts.Len() && ts.rows[k] == i && ts.cols[k] == j; k++ {
+			sum += ts.vals[k]
+		}
+		if sum != 0.0 {
+			sparse.cols = append(sparse.cols, j)
+			sparse.vals = append(sparse.vals, sum)
+			sparse.rowStart[i+1]++
+		}
+	}
+	for i := 0; i < builder.length; i++ {
+		sparse.rowStart[i+1] += sparse.rowStart[i]
+	}
+	return sparse
+}
+
+// Return the length of the matrix represented by sparse.
+func (sparse *SparseSymmetricMatrix) Length() int {
+	return sparse.length
+}
+
+// Return the number of stored (nonzero) elements, counting both triangles.
+func (sparse *SparseSymmetricMatrix) NonzeroCount() int {
+	return len(sparse.vals)
+}
+
+// Return the value at row i, column j in sparse.
+func (sparse *SparseSymmetricMatrix) Get(i, j int) float64 {
+	if i >= sparse.length || j >= sparse.length || i < 0 || j < 0 {
+		panic("matrix access out of bounds")
+	}
+	start, stop := sparse.rowStart[i], sparse.rowStart[i+1]
+	k := start + sort.SearchInts(sparse.cols[start:stop], j)
+	if k < stop && sparse.cols[k] == j {
+		return sparse.vals[k]
+	}
+	return 0.0
+}
+
+// Set y = sparse * x.  x and y must have length sparse.Length() and must not
+// overlap.  Has the signature of a LinearOperator.
+func (sparse *SparseSymmetricMatrix) MatVec(x, y []float64) {
+	for i := 0; i < sparse.length; i++ {
+		sum := 0.0
+		for k := sparse.rowStart[i]; k < sparse.rowStart[i+1]; k++ {
+			sum += sparse.vals[k] * x[sparse.cols[k]]
+		}
+		y[i] = sum
+	}
+}
+
+// Return the diagonal elements of sparse.
+func (sparse *SparseSymmetricMatrix) Diagonal() []float64 {
+	diag := make([]float64, sparse.length)
+	for i := range diag {
+		diag[i] = sparse.Get(i, i)
+	}
+	return diag
+}
+
+// Return the dense representation of sparse.
+func (sparse *SparseSymmetricMatrix) Dense() [][]float64 {
+	dense := make([][]float64, sparse.length)
+	for i := range dense {
+		dense[i] = make([]float64, sparse.length)
+		for k := sparse.rowStart[i]; k < sparse.rowStart[i+1]; k++ {
+			dense[i][sparse.cols[k]] = sparse.vals[k]
+		}
+	}
+	return dense
+}
+
+// Return the SymmetricMatrix representation of sparse.
+func (sparse *SparseSymmetricMatrix) Symmetric() *SymmetricMatrix {
+	sym := NewSymmetricMatrix(sparse.length)
+	for i := 0; i < sparse.length; i++ {
+		for k := sparse.rowStart[i]; k < sparse.rowStart[i+1]; k++ {
+			if j := sparse.cols[k]; j >= i {
+				sym.Set(i, j, sparse.vals[k])
+			}
+		}
+	}
+	return sym
+}
+
+// Return an ordered slice of the eigenvalues of sparse, and a slice of the
+// eigenvectors, padded as in SymmetricMatrix.Eigensystem.  Returns a GSLError
+// if GSL fails.
+func (sparse *SparseSymmetricMatrix) Eigensystem() ([]float64, [][]float64, error) {
+	return defaultEigenSolver.Eigensystem(sparse)
+}
+
+// Return the eigenvalues of sparse without computing the eigenvectors.
+func (sparse *SparseSymmetricMatrix) Eigenvalues() ([]float64, error) {
+	return defaultEigenSolver.Eigenvalues(sparse)
+}
+
+// Return true for each row of sparse with a nonzero element.
+func (sparse *SparseSymmetricMatrix) nonEmptyRows() []bool {
+	nonEmpty := make([]bool, sparse.length)
+	for i := range nonEmpty {
+		nonEmpty[i] = sparse.rowStart[i+1] > sparse.rowStart[i]
+	}
+	return nonEmpty
+}
+
+// Write the elements of sparse into the zeroed GSL matrix, moving the element
+// (i, j) to (newIndex[i], newIndex[j]).
+func (sparse *SparseSymmetricMatrix) setReduced(matrix *C.gsl_matrix, newIndex []int) {
+	for i := 0; i < sparse.length; i++ {
+		for k := sparse.rowStart[i]; k < sparse.rowStart[i+1]; k++ {
+			it, jt := C.size_t(newIndex[i]), C.size_t(newIndex[sparse.cols[k]])
+			C.gsl_matrix_set(matrix, it, jt, C.double(sparse.vals[k]))
+		}
+	}
+}
+
+func (sparse *SparseSymmetricMatrix) String() string {
+	out := ""
+	for _, row := range sparse.Dense() {
+		outRow := ""
+		for _, val := range row {
+			outRow += fmt.Sprint(val) + " "
+		}
+		out += outRow + "\n"
+	}
+	return out
+}
@@ -0,0 +1,85 @@
+package vo2percolation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSparseBuilderSumsDuplicates(t *testing.T) {
+	builder := NewSparseBuilder(4)
+	builder.Add(0, 2, 1.0)
+	builder.Add(2, 0, 2.0)
+	builder.Add(3, 3, 5.0)
+	builder.Add(1, 3, 1.0)
+	builder.Add(3, 1, -1.0)
+	sparse := builder.Build()
+	if sparse.Get(0, 2) != 3.0 || sparse.Get(2, 0) != 3.0 || sparse.Get(3, 3) != 5.0 {
+		t.Fatalf("unexpected values in built matrix:\n%v", sparse)
+	}
+	// elements which cancel are not stored
+	if sparse.Get(1, 3) != 0.0 || sparse.NonzeroCount() != 3 {
+		t.Fatalf("unexpected stored elements in built matrix:\n%v", sparse)
+	}
+	diag := sparse.Diagonal()
+	if diag[0] != 0.0 || diag[3] != 5.0 {
+		t.Fatalf("unexpected diagonal %v", diag)
+	}
+}
+
+func TestSparseMatVec(t *testing.T) {
+	builder := NewSparseBuilder(3)
+	builder.Add(0, 0, 2.0)
+	builder.Add(0, 1, -1.0)
+	builder.Add(1, 1, 2.0)
+	builder.Add(1, 2, -1.0)
+	builder.Add(2, 2, 2.0)
+	sparse := builder.Build()
+	x, y := []float64{1.0, 2.0, 3.0}, make([]float64, 3)
+	sparse.MatVec(x, y)
+	dense := sparse.Dense()
+	for i := range y {
+		expected := 0.0
+		for j := range x {
+			expected += dense[i][j] * x[j]
+		}
+		if y[i] != expected {
+			t.Fatalf("MatVec gave %v; dense product gave %v at row %d", y[i], expected, i)
+		}
+	}
+}
+
+// The sparse electron Hamiltonian holds the same elements and eigenvalues as
+// the map-based one.
+func TestElectronHamiltonianSparse(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	grid, err := RandomConstrainedGrid(NewRandom(1), 6, 6, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	H, sparse := e.ElectronHamiltonian(grid), e.ElectronHamiltonianSparse(grid)
+	for b := range H {
+		if !sparse[b].Symmetric().Equals(H[b]) {
+			t.Fatalf("sparse Hamiltonian differs from map-based Hamiltonian")
+		}
+		evals, _, err := H[b].Eigensystem()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sparseEvals, _, err := sparse[b].Eigensystem()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(evals) != len(sparseEvals) {
+			t.Fatalf("sparse Hamiltonian has %d eigenvalues; expected %d", len(sparseEvals), len(evals))
+		}
+		for k := range evals {
+			if math.Abs(evals[k]-sparseEvals[k]) > 1e-12 {
+				t.Fatalf("sparse eigenvalue %v differs from %v", sparseEvals[k], evals[k])
+			}
+		}
+	}
+}
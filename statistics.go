@@ -0,0 +1,225 @@
+// Error analysis for correlated time series, such as the observables of
+// successive Monte Carlo sweeps (see ObservableSeries).  Consecutive samples
+// of a Markov chain are correlated, so the naive standard error of the mean
+// underestimates the true error by a factor of sqrt(2 tau), where tau is the
+// integrated autocorrelation time.  Both the autocorrelation time and the
+// binning (blocking) analysis estimate this factor; the jackknife extends the
+// binned errors to nonlinear functions of several averages.
+package vo2percolation
+
+import (
+	"fmt"
+	"math"
+)
+
+const SeriesLengthError = "series needs at least %d samples, got %d"
+const SeriesMismatchError = "series have different lengths"
+const JackknifeBlocksError = "jackknife needs between 2 and %d blocks, got %d"
+
+// Windowing constant of the automatic window for the integrated
+// autocorrelation time: the sum over lags stops at the first window W with
+// W >= autocorrelationWindowFactor * tau(W) (Sokal).
+const autocorrelationWindowFactor = 5.0
+
+// Smallest number of bins for which the binning analysis reports an error.
+const binningMinBins = 32
+
+// Summary of the error analysis of one series.
+type SeriesStatistics struct {
+	Samples int
+	Mean    float64
+	// Standard error assuming independent samples.
+	NaiveStdErr float64
+	// Integrated autocorrelation time (in samples) and the window used to
+	// compute it.
+	Tau    float64
+	Window int
+	// Samples / (2 Tau), the number of independent samples equivalent to
+	// the series.
+	EffectiveSamples float64
+	// Standard error from the autocorrelation time and from binning.
+	StdErr, BinningStdErr float64
+}
+
+// One level of a binning analysis.
+type BinningLevel struct {
+	// Samples per bin and number of bins.
+	BinSize, Bins int
+	// Standard error of the mean computed from the bin averages.
+	StdErr float64
+}
+
+// Return the normalized autocorrelation function rho(t) of xs for lags
+// t = 0, ..., maxLag (rho(0) = 1).  A constant series has rho(t) = 0 for
+// t > 0.
+func Autocorrelation(xs []float64, maxLag int) []float64 {
+	if maxLag > len(xs)-1 {
+		maxLag = len(xs) - 1
+	}
+	m := mean(xs)
+	c0 := autocovariance(xs, m, 0)
+	rho := make([]float64, maxLag+1)
+	rho[0] = 1.0
+	if c0 == 0.0 {
+		return rho
+	}
+	for t := 1; t <= maxLag; t++ {
+		rho[t] = autocovariance(xs, m, t) / c0
+	}
+	return rho
+}
+
+// Return sum_i (xs[i] - m) (xs[i+t] - m).
+func autocovariance(xs []float64, m float64, t int) float64 {
+	ct := 0.0
+	for i := 0; i+t < len(xs); i++ {
+		ct += (xs[i] - m) * (xs[i+t] - m)
+	}
+	return ct
+}
+
+// Return the integrated autocorrelation time tau = 1/2 + sum_t rho(t) of xs,
+// summed over lags up to an automatically chosen window, and the window.
+// Independent samples have tau = 1/2.
+func IntegratedAutocorrelationTime(xs []float64) (float64, int, error) {
+	if len(xs) < 2 {
+		return 0.0, 0, fmt.Errorf(SeriesLengthError, 2, len(xs))
+	}
+	m := mean(xs)
+	c0 := autocovariance(xs, m, 0)
+	if c0 == 0.0 {
+		return 0.5, 0, nil
+	}
+	// rho(t) is computed only up to the window, which is usually short
+	tau := 0.5
+	for W := 1; W < len(xs); W++ {
+		tau += autocovariance(xs, m, W) / c0
+		if float64(W) >= autocorrelationWindowFactor*tau {
+			return tau, W, nil
+		}
+	}
+	// no window satisfied the criterion: the series is too short compared
+	// to its correlation time, so tau is only a lower bound
+	return tau, len(xs) - 1, nil
+}
+
+// Return the number of independent samples equivalent to xs.
+func EffectiveSampleSize(xs []float64) (float64, error) {
+	tau, _, err := IntegratedAutocorrelationTime(xs)
+	if err != nil {
+		return 0.0, err
+	}
+	return float64(len(xs)) / (2.0 * tau), nil
+}
+
+// Return the binning analysis of xs: the standard error of the mean computed
+// from averages over bins of 1, 2, 4, ... samples, down to two bins.  Samples
+// at the end of xs which don't fill a bin are left out.  For correlated data
+// the error grows with the bin size until the bins are longer than the
+// correlation time and then levels off.
+func BinningAnalysis(xs []float64) []BinningLevel {
+	levels := []BinningLevel{}
+	bins := make([]float64, len(xs))
+	copy(bins, xs)
+	for binSize := 1; len(bins) >= 2; binSize *= 2 {
+		levels = append(levels, BinningLevel{binSize, len(bins), math.Sqrt(variance(bins) / float64(len(bins)))})
+		next := make([]float64, len(bins)/2)
+		for i := range next {
+			next[i] = (bins[2*i] + bins[2*i+1]) / 2.0
+		}
+		bins = next
+	}
+	return levels
+}
+
+// Return the binning estimate of the standard error of the mean of xs: the
+// largest error among the levels of BinningAnalysis with at least
+// binningMinBins bins.
+func BinningError(xs []float64) (float64, error) {
+	if len(xs) < binningMinBins {
+		return 0.0, fmt.Errorf(SeriesLengthError, binningMinBins, len(xs))
+	}
+	stdErr := 0.0
+	for _, level := range BinningAnalysis(xs) {
+		if level.Bins >= binningMinBins {
+			stdErr = math.Max(stdErr, level.StdErr)
+		}
+	}
+	return stdErr, nil
+}
+
+// Return the error analysis of xs.  BinningStdErr is NaN if xs is too short
+// for BinningError.
+func AnalyzeSeries(xs []float64) (*SeriesStatistics, error) {
+	tau, window, err := IntegratedAutocorrelationTime(xs)
+	if err != nil {
+		return nil, err
+	}
+	n := float64(len(xs))
+	stats := new(SeriesStatistics)
+	stats.Samples = len(xs)
+	stats.Mean = mean(xs)
+	stats.NaiveStdErr = math.Sqrt(variance(xs) / n)
+	stats.Tau, stats.Window = tau, window
+	stats.EffectiveSamples = n / (2.0 * tau)
+	stats.StdErr = stats.NaiveStdErr * math.Sqrt(2.0*tau)
+	stats.BinningStdErr, err = BinningError(xs)
+	if err != nil {
+		stats.BinningStdErr = math.NaN()
+	}
+	return stats, nil
+}
+
+// Jackknife estimate and standard error of f(<x_0>, <x_1>, ...), where
+// series[k] holds the samples of x_k (all series taken at the same times).
+// The samples are grouped into blocks contiguous blocks, which should be
+// longer than the autocorrelation time; f is evaluated on the averages with
+// each block left out in turn.  The returned estimate is bias-corrected.
+// For example, the specific heat from energy samples E is f(<E>, <E^2>) =
+// Beta^2 (<E^2> - <E>^2), and the Binder cumulant from m is
+// f(<m^2>, <m^4>) = 1 - <m^4> / (3 <m^2>^2).
+func Jackknife(series [][]float64, f func(means []float64) float64, blocks int) (float64, float64, error) {
+	if len(series) == 0 {
+		return 0.0, 0.0, fmt.Errorf(SeriesLengthError, 1, 0)
+	}
+	n := len(series[0])
+	for _, xs := range series {
+		if len(xs) != n {
+			return 0.0, 0.0, fmt.Errorf(SeriesMismatchError)
+		}
+	}
+	if blocks < 2 || blocks > n {
+		return 0.0, 0.0, fmt.Errorf(JackknifeBlocksError, n, blocks)
+	}
+	blockSize := n / blocks
+	used := blockSize * blocks
+	// sum of each series over each block and over all blocks
+	blockSums := make([][]float64, len(series))
+	totals := make([]float64, len(series))
+	for k, xs := range series {
+		blockSums[k] = make([]float64, blocks)
+		for i := 0; i < used; i++ {
+			blockSums[k][i/blockSize] += xs[i]
+			totals[k] += xs[i]
+		}
+	}
+	means := make([]float64, len(series))
+	for k := range series {
+		means[k] = totals[k] / float64(used)
+	}
+	full := f(means)
+	leaveOut := make([]float64, blocks)
+	for b := 0; b < blocks; b++ {
+		for k := range series {
+			means[k] = (totals[k] - blockSums[k][b]) / float64(used-blockSize)
+		}
+		leaveOut[b] = f(means)
+	}
+	B := float64(blocks)
+	leaveOutMean := mean(leaveOut)
+	sum := 0.0
+	for _, val := range leaveOut {
+		sum += (val - leaveOutMean) * (val - leaveOutMean)
+	}
+	return B*full - (B-1.0)*leaveOutMean, math.Sqrt((B - 1.0) / B * sum), nil
+}
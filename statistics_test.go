@@ -0,0 +1,88 @@
+package vo2percolation
+
+import (
+	"math"
+	"testing"
+)
+
+// Return n samples of the AR(1) process x_t = a x_{t-1} + noise, which has
+// integrated autocorrelation time (1 + a) / (2 (1 - a)).
+func autoregressiveSeries(a float64, n int, seed int64) []float64 {
+	rng := NewRandom(seed)
+	xs := make([]float64, n)
+	x := 0.0
+	for i := range xs {
+		x = a*x + rng.NormFloat64()
+		xs[i] = x
+	}
+	return xs
+}
+
+func TestAutocorrelationTimeAR1(t *testing.T) {
+	for _, a := range []float64{0.0, 0.5, 0.8} {
+		xs := autoregressiveSeries(a, 200000, 3)
+		expected := (1 + a) / (2 * (1 - a))
+		stats, err := AnalyzeSeries(xs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(stats.Tau-expected) > 0.1*expected {
+			t.Fatalf("a = %v: tau %v; expected %v", a, stats.Tau, expected)
+		}
+		if math.Abs(stats.EffectiveSamples-float64(len(xs))/(2*expected)) > 0.1*float64(len(xs))/(2*expected) {
+			t.Fatalf("a = %v: unexpected effective sample size %v", a, stats.EffectiveSamples)
+		}
+		// binning and autocorrelation errors agree
+		if math.Abs(stats.BinningStdErr-stats.StdErr) > 0.15*stats.StdErr {
+			t.Fatalf("a = %v: binning error %v and autocorrelation error %v disagree", a, stats.BinningStdErr, stats.StdErr)
+		}
+	}
+}
+
+func TestBinningAnalysisLevels(t *testing.T) {
+	xs := autoregressiveSeries(0.5, 1000, 4)
+	levels := BinningAnalysis(xs)
+	if levels[0].BinSize != 1 || levels[0].Bins != 1000 {
+		t.Fatalf("unexpected first binning level %v", levels[0])
+	}
+	last := levels[len(levels)-1]
+	if last.Bins != 3 || last.BinSize != 256 {
+		t.Fatalf("unexpected last binning level %v", last)
+	}
+	if _, err := BinningError(xs[:10]); err == nil {
+		t.Fatalf("accepted series too short for binning")
+	}
+}
+
+func TestJackknife(t *testing.T) {
+	xs := autoregressiveSeries(0.0, 10000, 5)
+	// for the mean with one sample per block the jackknife error is the
+	// naive standard error
+	val, stdErr, err := Jackknife([][]float64{xs}, func(means []float64) float64 {
+		return means[0]
+	}, len(xs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	naive := math.Sqrt(variance(xs) / float64(len(xs)))
+	if math.Abs(val-mean(xs)) > 1e-12 || math.Abs(stdErr-naive) > 1e-12 {
+		t.Fatalf("jackknife mean %v +- %v; expected %v +- %v", val, stdErr, mean(xs), naive)
+	}
+	// the variance <x^2> - <x>^2 of unit normal noise is 1
+	squares := make([]float64, len(xs))
+	for i, x := range xs {
+		squares[i] = x * x
+	}
+	val, stdErr, err = Jackknife([][]float64{xs, squares}, func(means []float64) float64 {
+		return means[1] - means[0]*means[0]
+	}, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdErr <= 0 || math.Abs(val-1.0) > 4*stdErr {
+		t.Fatalf("jackknife variance %v +- %v; expected 1", val, stdErr)
+	}
+	if _, _, err := Jackknife([][]float64{xs, squares[1:]}, nil, 10); err == nil {
+		t.Fatalf("accepted series of different lengths")
+	}
+}
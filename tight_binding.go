@@ -0,0 +1,335 @@
+// Multi-orbital tight-binding model for the electrons on the metallic network.
+// A model lists orbitals with their on-site energies, and hopping amplitudes
+// between pairs of orbitals on neighboring sites for each neighbor class
+// ("dimer" or "diag").  Models can be read from JSON, for example:
+//
+//	{
+//		"Orbitals": [{"Name": "a1g", "Epsilon": 1.0}, {"Name": "epi", "Epsilon": 1.0}],
+//		"Hoppings": [
+//			{"Neighbor": "dimer", "From": "a1g", "To": "a1g", "T": 1.0},
+//			{"Neighbor": "dimer", "From": "a1g", "To": "epi", "T": 0.2}
+//		]
+//	}
+//
+// A hopping between orbitals a and b on a neighbor pair (i, j) joins a on i
+// to b on j and b on i to a on j, giving matrix elements -T.
+package vo2percolation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/cmplx"
+)
+
+const DimerNeighbor = "dimer"
+const DiagNeighbor = "diag"
+
+const ModelOrbitalError = "tight-binding model must have at least one orbital, each with a unique name"
+const ModelHoppingError = "tight-binding model hopping %v refers to an unknown orbital or neighbor class"
+const ModelDimerEnergyError = "effective hopping through dimers requires Epsilon_dimer to differ from all orbital energies"
+
+type TightBindingModel struct {
+	Orbitals []OrbitalSpec
+	Hoppings []HoppingSpec
+}
+
+type OrbitalSpec struct {
+	Name    string
+	Epsilon float64 // on-site energy
+}
+
+type HoppingSpec struct {
+	Neighbor string // DimerNeighbor or DiagNeighbor
+	From, To string // orbital names
+	T        float64
+}
+
+// Build the two-orbital model described by the fields of env: the a_1g
+// (alpha) orbital hops only in the dimer direction; the e_pi (beta) orbital
+// hops in both the dimer and diagonal directions.
+func ModelFromEnvironment(env Environment) *TightBindingModel {
+	return &TightBindingModel{
+		[]OrbitalSpec{{"alpha", env.Epsilon_alpha}, {"beta", env.Epsilon_beta}},
+		[]HoppingSpec{
+			{DimerNeighbor, "alpha", "alpha", env.T_alpha},
+			{DimerNeighbor, "beta", "beta", env.T_beta_dimer},
+			{DiagNeighbor, "beta", "beta", env.T_beta_diag},
+		},
+	}
+}
+
+// Build a TightBindingModel from the JSON file at filePath.
+func TightBindingModelFromFile(filePath string) (*TightBindingModel, error) {
+	fileContents, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return tightBindingModelFromBytes(fileContents)
+}
+
+// Build a TightBindingModel from the given JSON string.
+func TightBindingModelFromString(jsonData string) (*TightBindingModel, error) {
+	return tightBindingModelFromBytes([]byte(jsonData))
+}
+
+func tightBindingModelFromBytes(jsonData []byte) (*TightBindingModel, error) {
+	model := new(TightBindingModel)
+	if err := json.Unmarshal(jsonData, model); err != nil {
+		return nil, err
+	}
+	if err := model.validate(); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// Return an error if the model is inconsistent.
+func (m *TightBindingModel) validate() error {
+	if len(m.Orbitals) == 0 {
+		return fmt.Errorf(ModelOrbitalError)
+	}
+	names := make(map[string]bool)
+	for _, orb := range m.Orbitals {
+		if names[orb.Name] {
+			return fmt.Errorf(ModelOrbitalError)
+		}
+		names[orb.Name] = true
+	}
+	for _, hop := range m.Hoppings {
+		if !names[hop.From] || !names[hop.To] {
+			return fmt.Errorf(ModelHoppingError, hop)
+		}
+		if hop.Neighbor != DimerNeighbor && hop.Neighbor != DiagNeighbor {
+			return fmt.Errorf(ModelHoppingError, hop)
+		}
+	}
+	return nil
+}
+
+// Return an error if the model can't be used with env.
+func (m *TightBindingModel) validateWith(env Environment) error {
+	if !env.Renormalize_dimers {
+		return nil
+	}
+	for _, orb := range m.Orbitals {
+		if orb.Epsilon == env.Epsilon_dimer {
+			return fmt.Errorf(ModelDimerEnergyError)
+		}
+	}
+	return nil
+}
+
+// Return the index of each orbital name.
+func (m *TightBindingModel) orbitalIndices() map[string]int {
+	indices := make(map[string]int)
+	for i, orb := range m.Orbitals {
+		indices[orb.Name] = i
+	}
+	return indices
+}
+
+// Return the hopping amplitudes amp[class][a][b] between orbitals a and b
+// for each neighbor class.  amp[class] is symmetric.
+func (m *TightBindingModel) amplitudes() map[string][][]float64 {
+	indices := m.orbitalIndices()
+	amp := make(map[string][][]float64)
+	for _, class := range []string{DimerNeighbor, DiagNeighbor} {
+		amp[class] = make([][]float64, len(m.Orbitals))
+		for a := range amp[class] {
+			amp[class][a] = make([]float64, len(m.Orbitals))
+		}
+	}
+	for _, hop := range m.Hoppings {
+		a, b := indices[hop.From], indices[hop.To]
+		amp[hop.Neighbor][a][b] += hop.T
+		if a != b {
+			amp[hop.Neighbor][b][a] += hop.T
+		}
+	}
+	return amp
+}
+
+// Return the orbitals of each block of the Hamiltonian.  Orbitals joined by
+// a nonzero inter-orbital hopping are in the same block; different blocks
+// are decoupled.
+func (m *TightBindingModel) Blocks() [][]int {
+	// union-find over orbitals
+	parent := make([]int, len(m.Orbitals))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	indices := m.orbitalIndices()
+	for _, hop := range m.Hoppings {
+		if hop.T != 0.0 {
+			parent[find(indices[hop.From])] = find(indices[hop.To])
+		}
+	}
+	blocks := [][]int{}
+	blockOf := make(map[int]int)
+	for i := range m.Orbitals {
+		root := find(i)
+		b, ok := blockOf[root]
+		if !ok {
+			b = len(blocks)
+			blockOf[root] = b
+			blocks = append(blocks, []int{})
+		}
+		blocks[b] = append(blocks[b], i)
+	}
+	return blocks
+}
+
+// Function type for adding x to the element (i, j) of the Hamiltonian of
+// block b (and the conjugate of x to the element (j, i)).
+type hamiltonianAdder func(b, i, j int, x complex128)
+
+// Build the Hamiltonian for the electrons on g with add.  Within block b the
+// row of local orbital l at the site with 1D index id is l * Lx * Ly + id.
+// phase(p, n) multiplies the hopping from n to p.  If env.Renormalize_dimers
+// is set, active sites which share an inactive neighbor d are joined by the
+// second-order hopping sum_c t(a, c) t(c, b) / (epsilon - Epsilon_dimer),
+// where epsilon is the mean on-site energy of a and b.
+func (m *TightBindingModel) assemble(env *Environment, g *Grid, phase func(p, n Point) complex128, add hamiltonianAdder) {
+	blocks := m.Blocks()
+	amp := m.amplitudes()
+	N := g.Lx() * g.Ly()
+	convert := g.ConvertTo1D()
+	// neighbor class of the neighboring points p and n
+	class := func(p, n Point) string {
+		if p.Y() == n.Y() {
+			return DimerNeighbor
+		}
+		return DiagNeighbor
+	}
+	for _, p := range g.ActiveSites().Elements() {
+		id := convert(p)
+		for b, orbs := range blocks {
+			// on-site energy
+			for l, a := range orbs {
+				add(b, l*N+id, l*N+id, complex(m.Orbitals[a].Epsilon, 0))
+			}
+			for _, n := range g.Neighbors(p) {
+				if g.Get(n) {
+					// direct hopping
+					nId := convert(n)
+					t := amp[class(p, n)]
+					for l, a := range orbs {
+						for k, c := range orbs {
+							if t[a][c] != 0.0 {
+								// factor of 1/2 due to double counting
+								x := complex(-t[a][c]/2, 0) * phase(p, n)
+								add(b, l*N+id, k*N+nId, x)
+							}
+						}
+					}
+					continue
+				}
+				if !env.Renormalize_dimers {
+					continue
+				}
+				// effective hopping through the inactive site d = n
+				d := n
+				t1 := amp[class(p, d)]
+				for _, q := range g.Neighbors(d) {
+					if q == p || !g.Get(q) {
+						continue
+					}
+					qId := convert(q)
+					t2 := amp[class(d, q)]
+					for l, a := range orbs {
+						for k, c := range orbs {
+							sum := 0.0
+							for mid := range m.Orbitals {
+								sum += t1[a][mid] * t2[mid][c]
+							}
+							if sum == 0.0 {
+								continue
+							}
+							epsilon := (m.Orbitals[a].Epsilon + m.Orbitals[c].Epsilon) / 2
+							// factor of 1/2 due to double counting
+							x := complex(sum/(epsilon-env.Epsilon_dimer)/2, 0) * phase(p, d) * phase(d, q)
+							add(b, l*N+id, k*N+qId, x)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// Return the real symmetric Hamiltonian of each block for the electrons on g.
+func (m *TightBindingModel) Hamiltonian(env *Environment, g *Grid) []*SymmetricMatrix {
+	N := g.Lx() * g.Ly()
+	blocks := m.Blocks()
+	H := make([]*SymmetricMatrix, len(blocks))
+	builders := make([]SymmetricBuilder, len(blocks))
+	for b, orbs := range blocks {
+		H[b] = NewSymmetricMatrix(len(orbs) * N)
+		builders[b] = H[b]
+	}
+	m.assembleReal(env, g, builders)
+	return H
+}
+
+// Return the Hamiltonian of each block for the electrons on g in compressed
+// sparse row form.
+func (m *TightBindingModel) HamiltonianSparse(env *Environment, g *Grid) []*SparseSymmetricMatrix {
+	N := g.Lx() * g.Ly()
+	blocks := m.Blocks()
+	sparseBuilders := make([]*SparseBuilder, len(blocks))
+	builders := make([]SymmetricBuilder, len(blocks))
+	for b, orbs := range blocks {
+		sparseBuilders[b] = NewSparseBuilder(len(orbs) * N)
+		builders[b] = sparseBuilders[b]
+	}
+	m.assembleReal(env, g, builders)
+	H := make([]*SparseSymmetricMatrix, len(blocks))
+	for b, builder := range sparseBuilders {
+		H[b] = builder.Build()
+	}
+	return H
+}
+
+// Add the real Hamiltonian of each block for the electrons on g to the
+// corresponding builder.
+func (m *TightBindingModel) assembleReal(env *Environment, g *Grid, builders []SymmetricBuilder) {
+	noPhase := func(p, n Point) complex128 {
+		return 1
+	}
+	m.assemble(env, g, noPhase, func(b, i, j int, x complex128) {
+		builders[b].Add(i, j, real(x))
+	})
+}
+
+// Return the Hermitian Hamiltonian of each block for the electrons on g in a
+// perpendicular magnetic field env.B.  Each hopping from n to p picks up a
+// Peierls phase exp(i theta) with theta = 2 pi \int_n^p A . dl in the Landau
+// gauge A = (-B y, 0), so that the phases around any closed loop add up to
+// 2 pi B times the enclosed area.
+func (m *TightBindingModel) HamiltonianPeierls(env *Environment, g *Grid) []*HermitianMatrix {
+	N := g.Lx() * g.Ly()
+	blocks := m.Blocks()
+	H := make([]*HermitianMatrix, len(blocks))
+	for b, orbs := range blocks {
+		H[b] = NewHermitianMatrix(len(orbs) * N)
+	}
+	peierls := func(p, n Point) complex128 {
+		xp, yp := g.Position(p)
+		xn, yn := g.Position(n)
+		theta := -2.0 * math.Pi * env.B * (xp - xn) * (yp + yn) / 2.0
+		return cmplx.Exp(complex(0, theta))
+	}
+	m.assemble(env, g, peierls, func(b, i, j int, x complex128) {
+		H[b].Add(i, j, x)
+	})
+	return H
+}
@@ -0,0 +1,91 @@
+package vo2percolation
+
+import (
+	"math"
+	"testing"
+)
+
+// A model file describing the default orbitals gives the same Hamiltonian as
+// the Environment fields.
+func TestTightBindingModelMatchesEnvironment(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := TightBindingModelFromString(`{
+		"Orbitals": [{"Name": "a", "Epsilon": 1.0}, {"Name": "b", "Epsilon": 1.0}],
+		"Hoppings": [
+			{"Neighbor": "dimer", "From": "a", "To": "a", "T": 1.0},
+			{"Neighbor": "dimer", "From": "b", "To": "b", "T": 1.0},
+			{"Neighbor": "diag", "From": "b", "To": "b", "T": 1.0}
+		]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromModel, err := NewEnergeticsWithModel(*env, model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grid, err := RandomConstrainedGrid(NewRandom(1), 6, 6, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := NewEnergetics(*env).ElectronHamiltonian(grid)
+	H := fromModel.ElectronHamiltonian(grid)
+	if len(H) != len(expected) {
+		t.Fatalf("unexpected number of Hamiltonian blocks")
+	}
+	for b := range H {
+		if !H[b].Equals(expected[b]) {
+			t.Fatalf("model Hamiltonian differs from Environment Hamiltonian")
+		}
+	}
+}
+
+// Inter-orbital hopping joins orbitals into a single block.
+func TestTightBindingModelT2g(t *testing.T) {
+	model, err := TightBindingModelFromFile("t2g.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := model.Blocks()
+	if len(blocks) != 1 || len(blocks[0]) != 3 {
+		t.Fatalf("unexpected orbital blocks %v", blocks)
+	}
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewEnergeticsWithModel(*env, model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grid := activeGrid(4, 3)
+	H := e.ElectronHamiltonian(grid)
+	N := grid.Lx() * grid.Ly()
+	if H[0].Length() != 3*N {
+		t.Fatalf("unexpected Hamiltonian size")
+	}
+	// a1g at (1, 0) couples to epi1 at its diagonal neighbor (1, 1)
+	convert := grid.ConvertTo1D()
+	if H[0].Get(convert(Point{1, 0}), N+convert(Point{1, 1})) != -0.2 {
+		t.Fatalf("missing inter-orbital hopping")
+	}
+	// clean strips transmit an integer number of channels
+	trans, err := e.Transmission(grid, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(trans-math.Floor(trans+0.5)) > 1e-4 {
+		t.Fatalf("non-integer transmission %v through clean strip", trans)
+	}
+}
+
+func TestTightBindingModelUnknownOrbital(t *testing.T) {
+	_, err := TightBindingModelFromString(`{
+		"Orbitals": [{"Name": "a", "Epsilon": 1.0}],
+		"Hoppings": [{"Neighbor": "dimer", "From": "a", "To": "b", "T": 1.0}]}`)
+	if err == nil {
+		t.Fatalf("accepted hopping to unknown orbital")
+	}
+}
@@ -0,0 +1,420 @@
+// Two-terminal Landauer transport through the metallic network.
+// Ideal leads are attached to the left (x = 0) and right (x = Lx - 1) edges of
+// the grid; each lead is a semi-infinite, fully metallic continuation of the
+// lattice in the x direction.  The transmission is found with a recursive
+// Green's function sweep over the columns of the grid.  With
+// Renormalize_dimers the effective hopping through an inactive site joins
+// columns two apart, so the sweep runs over slabs of two columns instead.
+package vo2percolation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+const SingularMatrixError = "matrix is singular"
+const LeadConvergenceError = "lead surface Green's function did not converge"
+
+// Returned by decimate when the decimation does not converge at the given
+// energy, so that surfaceGreen can retry.
+var errDecimationDiverged = errors.New(LeadConvergenceError)
+
+// Small imaginary part added to the energy to select the retarded Green's
+// function.  Transmissions are accurate to roughly 1e-6 inside the bands; at
+// energies equal to a lead on-site energy the decimation loses precision and
+// the error grows to roughly 1e-4.  At an eigenvalue of the lead unit cell the
+// lead decimation is retried with the imaginary part raised up to 1000-fold
+// (see surfaceGreen), and the extra broadening can lower the accuracy there by
+// the same factor, to roughly 1e-3.
+const greenEta = 1e-7
+
+// Convergence criterion, iteration cap and divergence threshold for the lead
+// decimation, and the number of times it is retried with a larger imaginary
+// part of the energy.
+const leadEpsilon = 1e-12
+const leadMaxIter = 256
+const leadDivergence = 1e100
+const leadEtaRetries = 3
+
+// Dense complex matrix used for the Green's function blocks.
+type cmatrix [][]complex128
+
+// Total transmission T(energy) from the left lead to the right lead, summed
+// over the independent orbital blocks of ElectronHamiltonian.  Spin is not
+// included.
+func (e *Energetics) Transmission(g *Grid, energy float64) (float64, error) {
+	device := e.ElectronHamiltonian(g)
+	lead := e.ElectronHamiltonian(leadGrid(g.Ly()))
+	slabWidth := 1
+	if e.env.Renormalize_dimers {
+		slabWidth = 2
+	}
+	slabs := activeSlabs(g, slabWidth)
+	total := 0.0
+	for i := range device {
+		t, err := blockTransmission(device[i], lead[i], g, slabs, energy)
+		if err != nil {
+			return 0.0, err
+		}
+		total += t
+	}
+	return total, nil
+}
+
+// Two-terminal conductance at the Fermi level in units of e^2/h, including
+// the factor of two for spin.  The Fermi level is found as in FermiEnergy
+// with one electron per active site.  A grid with no active sites has zero
+// conductance.
+func (e *Energetics) Conductance(g *Grid) (float64, error) {
+	particleCount := g.ActiveSiteCount()
+	if particleCount == 0 {
+		return 0.0, nil
+	}
+	fermi, err := e.FermiEnergy(g, particleCount)
+	if err != nil {
+		return 0.0, err
+	}
+	t, err := e.Transmission(g, fermi)
+	if err != nil {
+		return 0.0, err
+	}
+	return 2.0 * t, nil
+}
+
+// Return a fully active grid two columns wide, which contains one unit cell of
+// an ideal lead and its coupling to the next cell.
+func leadGrid(Ly int) *Grid {
+	lead := NewGridWithDims(2, Ly)
+	lead.Iterate(func(p Point, value bool) {
+		lead.Set(p, true)
+	})
+	return lead
+}
+
+// Return the active sites of each slab of width consecutive columns of g,
+// ordered by x and then y.  The last slab is narrower if width doesn't divide
+// Lx.
+func activeSlabs(g *Grid, width int) [][]Point {
+	slabs := make([][]Point, (g.Lx()+width-1)/width)
+	for x := 0; x < g.Lx(); x++ {
+		for y := 0; y < g.Ly(); y++ {
+			p := Point{x, y}
+			if g.Get(p) {
+				slabs[x/width] = append(slabs[x/width], p)
+			}
+		}
+	}
+	return slabs
+}
+
+// Transmission through a single block of orbitals.  H is the device Hamiltonian
+// on g and leadH is the Hamiltonian on leadGrid(g.Ly()).  slabs holds the
+// active sites of groups of columns such that H couples only sites in the same
+// or neighboring groups.
+func blockTransmission(H, leadH *SymmetricMatrix, g *Grid, slabs [][]Point, energy float64) (float64, error) {
+	// any slab without active sites blocks transport entirely
+	for _, slab := range slabs {
+		if len(slab) == 0 {
+			return 0.0, nil
+		}
+	}
+	z := complex(energy, greenEta)
+	Lx, Ly := g.Lx(), g.Ly()
+	N, leadN := Lx*Ly, 2*Ly
+	numOrbitals := H.Length() / N
+	convert, leadConvert := g.ConvertTo1D(), leadGrid(Ly).ConvertTo1D()
+	// lead unit cell Hamiltonian and the coupling from one cell to the next;
+	// the lead row of local orbital l at height y is l * Ly + y
+	leadCol0, leadCol1 := []int{}, []int{}
+	for l := 0; l < numOrbitals; l++ {
+		for y := 0; y < Ly; y++ {
+			leadCol0 = append(leadCol0, l*leadN+leadConvert(Point{0, y}))
+			leadCol1 = append(leadCol1, l*leadN+leadConvert(Point{1, y}))
+		}
+	}
+	H00 := block(leadH, leadCol0, leadCol0)
+	H01 := block(leadH, leadCol0, leadCol1)
+	H10 := H01.dagger()
+	// surface Green's functions of the left and right leads
+	gL, err := surfaceGreen(z, H00, H10, H01)
+	if err != nil {
+		return 0.0, err
+	}
+	gR, err := surfaceGreen(z, H00, H01, H10)
+	if err != nil {
+		return 0.0, err
+	}
+	// device rows of each slab, and the matching lead rows for sites on
+	// the edges of the grid (-1 elsewhere)
+	S := len(slabs)
+	ids, leftIds, rightIds := make([][]int, S), make([][]int, S), make([][]int, S)
+	for s, slab := range slabs {
+		for l := 0; l < numOrbitals; l++ {
+			for _, p := range slab {
+				ids[s] = append(ids[s], l*N+convert(p))
+				left, right := -1, -1
+				if p.X() == 0 {
+					left = l*Ly + p.Y()
+				}
+				if p.X() == Lx-1 {
+					right = l*Ly + p.Y()
+				}
+				leftIds[s] = append(leftIds[s], left)
+				rightIds[s] = append(rightIds[s], right)
+			}
+		}
+	}
+	// couplings between the lead surfaces and the edge slabs of the device
+	leadSize := numOrbitals * Ly
+	tauL := newCMatrix(leadSize, len(ids[0]))
+	for b, leadB := range leftIds[0] {
+		if leadB < 0 {
+			continue
+		}
+		for a := 0; a < leadSize; a++ {
+			tauL[a][b] = H01[a][leadB]
+		}
+	}
+	tauR := newCMatrix(len(ids[S-1]), leadSize)
+	for a, leadA := range rightIds[S-1] {
+		if leadA < 0 {
+			continue
+		}
+		for b := 0; b < leadSize; b++ {
+			tauR[a][b] = H01[leadA][b]
+		}
+	}
+	sigmaL := tauL.dagger().mul(gL).mul(tauL)
+	sigmaR := tauR.mul(gR).mul(tauR.dagger())
+	// recursive sweep: GL is the Green's function of slabs 0..s connected
+	// to the left lead, G0 is its block between slab s and slab 0
+	var GL, G0 cmatrix
+	for s := 0; s < S; s++ {
+		inv := block(H, ids[s], ids[s]).scale(-1).addDiagonal(z)
+		if s == 0 {
+			inv = inv.sub(sigmaL)
+		} else {
+			hopBack := block(H, ids[s], ids[s-1])
+			inv = inv.sub(hopBack.mul(GL).mul(hopBack.dagger()))
+		}
+		if s == S-1 {
+			inv = inv.sub(sigmaR)
+		}
+		nextGL, err := inv.inverse()
+		if err != nil {
+			return 0.0, err
+		}
+		if s == 0 {
+			G0 = nextGL
+		} else {
+			G0 = nextGL.mul(block(H, ids[s], ids[s-1])).mul(G0)
+		}
+		GL = nextGL
+	}
+	gammaL, gammaR := broadening(sigmaL), broadening(sigmaR)
+	t := gammaR.mul(G0).mul(gammaL).mul(G0.dagger()).trace()
+	return real(t), nil
+}
+
+// Surface Green's function of a semi-infinite lead with unit cell Hamiltonian
+// H00, where away is the coupling from the surface cell to the next cell into
+// the lead and back = away^dagger.  Uses the decimation scheme of Lopez Sancho
+// et al., J. Phys. F 15, 851 (1985).  When real(z) is an eigenvalue of H00 the
+// decimation can blow up; it is then repeated with the imaginary part of z
+// raised tenfold at a time, up to leadEtaRetries times.
+func surfaceGreen(z complex128, H00, away, back cmatrix) (cmatrix, error) {
+	eta := imag(z)
+	for retry := 0; retry <= leadEtaRetries; retry++ {
+		gs, err := decimate(complex(real(z), eta), H00, away, back)
+		if err != errDecimationDiverged {
+			return gs, err
+		}
+		eta *= 10
+	}
+	return nil, fmt.Errorf(LeadConvergenceError)
+}
+
+// One attempt at the decimation of surfaceGreen at fixed z.
+func decimate(z complex128, H00, away, back cmatrix) (cmatrix, error) {
+	epsSurface, eps := H00.copy(), H00.copy()
+	alpha, beta := away.copy(), back.copy()
+	for iter := 0; iter < leadMaxIter; iter++ {
+		g, err := eps.scale(-1).addDiagonal(z).inverse()
+		if err != nil {
+			return nil, err
+		}
+		agb, bga := alpha.mul(g).mul(beta), beta.mul(g).mul(alpha)
+		epsSurface = epsSurface.add(agb)
+		eps = eps.add(agb).add(bga)
+		alpha, beta = alpha.mul(g).mul(alpha), beta.mul(g).mul(beta)
+		if alpha.maxAbs() < leadEpsilon && beta.maxAbs() < leadEpsilon {
+			return epsSurface.scale(-1).addDiagonal(z).inverse()
+		}
+		// diverging or NaN couplings will not recover
+		if !(alpha.maxAbs() < leadDivergence && beta.maxAbs() < leadDivergence) {
+			break
+		}
+	}
+	return nil, errDecimationDiverged
+}
+
+// Broadening matrix Gamma = i (Sigma - Sigma^dagger).
+func broadening(sigma cmatrix) cmatrix {
+	return sigma.sub(sigma.dagger()).scale(1i)
+}
+
+// Return the block of H with the given row and column indices.
+func block(H *SymmetricMatrix, rows, cols []int) cmatrix {
+	b := newCMatrix(len(rows), len(cols))
+	for i, r := range rows {
+		for j, c := range cols {
+			b[i][j] = complex(H.Get(r, c), 0)
+		}
+	}
+	return b
+}
+
+// Return a zeroed rows x cols complex matrix.
+func newCMatrix(rows, cols int) cmatrix {
+	m := make(cmatrix, rows)
+	for i := range m {
+		m[i] = make([]complex128, cols)
+	}
+	return m
+}
+
+func (m cmatrix) rows() int {
+	return len(m)
+}
+
+func (m cmatrix) cols() int {
+	if len(m) == 0 {
+		return 0
+	}
+	return len(m[0])
+}
+
+func (m cmatrix) copy() cmatrix {
+	c := newCMatrix(m.rows(), m.cols())
+	for i := range m {
+		copy(c[i], m[i])
+	}
+	return c
+}
+
+func (m cmatrix) mul(n cmatrix) cmatrix {
+	p := newCMatrix(m.rows(), n.cols())
+	for i := range m {
+		for k, mik := range m[i] {
+			if mik == 0 {
+				continue
+			}
+			for j, nkj := range n[k] {
+				p[i][j] += mik * nkj
+			}
+		}
+	}
+	return p
+}
+
+func (m cmatrix) add(n cmatrix) cmatrix {
+	s := m.copy()
+	for i := range s {
+		for j := range s[i] {
+			s[i][j] += n[i][j]
+		}
+	}
+	return s
+}
+
+func (m cmatrix) sub(n cmatrix) cmatrix {
+	return m.add(n.scale(-1))
+}
+
+func (m cmatrix) scale(x complex128) cmatrix {
+	s := m.copy()
+	for i := range s {
+		for j := range s[i] {
+			s[i][j] *= x
+		}
+	}
+	return s
+}
+
+// Return m + z*I (m must be square).
+func (m cmatrix) addDiagonal(z complex128) cmatrix {
+	s := m.copy()
+	for i := range s {
+		s[i][i] += z
+	}
+	return s
+}
+
+// Return the conjugate transpose of m.
+func (m cmatrix) dagger() cmatrix {
+	d := newCMatrix(m.cols(), m.rows())
+	for i := range m {
+		for j, val := range m[i] {
+			d[j][i] = cmplx.Conj(val)
+		}
+	}
+	return d
+}
+
+func (m cmatrix) trace() complex128 {
+	var t complex128
+	for i := range m {
+		t += m[i][i]
+	}
+	return t
+}
+
+// Largest absolute value of the elements of m.
+func (m cmatrix) maxAbs() float64 {
+	max := 0.0
+	for i := range m {
+		for _, val := range m[i] {
+			max = math.Max(max, cmplx.Abs(val))
+		}
+	}
+	return max
+}
+
+// Return the inverse of the square matrix m, found by Gauss-Jordan
+// elimination with partial pivoting.
+func (m cmatrix) inverse() (cmatrix, error) {
+	n := m.rows()
+	a := m.copy()
+	inv := newCMatrix(n, n).addDiagonal(1)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if cmplx.Abs(a[r][col]) > cmplx.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		if a[pivot][col] == 0 {
+			return nil, fmt.Errorf(SingularMatrixError)
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+		scale := 1 / a[col][col]
+		for j := 0; j < n; j++ {
+			a[col][j] *= scale
+			inv[col][j] *= scale
+		}
+		for r := 0; r < n; r++ {
+			if r == col || a[r][col] == 0 {
+				continue
+			}
+			factor := a[r][col]
+			for j := 0; j < n; j++ {
+				a[r][j] -= factor * a[col][j]
+				inv[r][j] -= factor * inv[col][j]
+			}
+		}
+	}
+	return inv, nil
+}
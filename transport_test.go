@@ -0,0 +1,113 @@
+package vo2percolation
+
+import (
+	"math"
+	"testing"
+)
+
+// Return an Lx by Ly grid where all sites are active.
+func activeGrid(Lx, Ly int) *Grid {
+	grid := NewGridWithDims(Lx, Ly)
+	grid.Iterate(func(p Point, val bool) {
+		grid.Set(p, true)
+	})
+	return grid
+}
+
+// A clean chain (Ly = 1) has one open channel per orbital inside the band.
+func TestTransmissionCleanChain(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	grid := activeGrid(4, 1)
+	trans, err := e.Transmission(grid, env.Epsilon_alpha+0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(trans-2.0) > 1e-4 {
+		t.Fatalf("unexpected transmission %v through clean chain", trans)
+	}
+}
+
+// A clean strip is a piece of the leads, so every channel is perfectly
+// transmitted and T(E) is an integer.
+func TestTransmissionCleanStripQuantized(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	grid := activeGrid(5, 4)
+	for _, energy := range []float64{-0.7, 0.3, 1.1, 2.4} {
+		trans, err := e.Transmission(grid, energy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(trans-math.Floor(trans+0.5)) > 1e-4 {
+			t.Fatalf("non-integer transmission %v through clean strip", trans)
+		}
+	}
+}
+
+// A fully dimerized column cuts the metallic network.
+func TestConductanceBlockedColumn(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	grid := activeGrid(5, 4)
+	for y := 0; y < grid.Ly(); y++ {
+		grid.Set(Point{2, y}, false)
+	}
+	G, err := e.Conductance(grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if G != 0.0 {
+		t.Fatalf("nonzero conductance across a dimerized column")
+	}
+}
+
+// With Renormalize_dimers, electrons tunnel through a fully dimerized column
+// between two metallic columns.
+func TestTransmissionThroughRenormalizedColumn(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Renormalize_dimers = true
+	env.Epsilon_dimer = -1.0
+	e := NewEnergetics(*env)
+	grid := activeGrid(3, 2)
+	for y := 0; y < grid.Ly(); y++ {
+		grid.Set(Point{1, y}, false)
+	}
+	trans, err := e.Transmission(grid, env.Epsilon_alpha+0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(trans > 1e-6) {
+		t.Fatalf("no transmission %v through renormalized dimer column", trans)
+	}
+}
+
+// At an eigenvalue of the lead unit cell (E = 0 for the beta orbitals of a
+// strip with Ly = 5) the lead decimation must still converge, and the clean
+// strip must stay quantized to within the reduced accuracy.
+func TestTransmissionAtLeadCellEigenvalue(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	trans, err := e.Transmission(activeGrid(4, 5), 0.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(trans-math.Floor(trans+0.5)) > 1e-3 {
+		t.Fatalf("non-integer transmission %v at lead cell eigenvalue", trans)
+	}
+}
@@ -0,0 +1,250 @@
+// Wang-Landau estimation of the density of states g of the atomic model.
+// The AtomicHamiltonian energy Delta * Na - V * Nd depends only on the number
+// of active sites Na and the number of dimers Nd, so macrostates are either
+// the distinct energy levels or (optionally) the (Na, Nd) pairs.  A random
+// walk over single-site flips accepts moves with probability
+// min(1, g(old) / g(new)) while adding ln f to ln g of each visited state.
+// When the visit histogram is flat, ln f is halved; the run ends when ln f
+// drops below lnfFinal.  ln g is normalized so that sum g = 2^(Lx * Ly).
+package vo2percolation
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+const WangLandauValidateError = "Wang-Landau input parameters are invalid"
+const WangLandauSweepError = "Wang-Landau did not converge within %d sweeps"
+
+// Energies closer than this are treated as one level.
+const wangLandauEnergyResolution = 1e-9
+
+// Parameters for a Wang-Landau run.
+type WangLandau struct {
+	// If true, resolve macrostates by (Na, Nd); otherwise by energy only.
+	joint bool
+	// Histogram is flat when min(H) >= flatness * mean(H) (0 < flatness < 1).
+	flatness float64
+	// Stop when ln f < lnfFinal.
+	lnfFinal float64
+	// Sweeps between checks of histogram flatness.
+	checkInterval int
+	// Give up after this many sweeps.
+	maxSweeps int
+	seed      int64
+	rng       *rand.Rand
+}
+
+// Density of states from a Wang-Landau run.
+type DensityOfStates struct {
+	// Number of sites in the grid.
+	Sites int
+	// Levels sorted by energy (then by Active, Dimers).
+	Levels []DOSLevel
+	// Sweeps taken and number of ln f stages completed.
+	Sweeps, Stages int
+}
+
+// One macrostate of the density of states.
+type DOSLevel struct {
+	// Active site and dimer counts; -1 if macrostates are not resolved by
+	// (Na, Nd).
+	Active, Dimers int
+	Energy         float64
+	LnG            float64
+	// Mean number of active sites among the visits to this level.
+	MeanActive float64
+}
+
+// Thermodynamic averages at one inverse temperature.
+type Thermodynamics struct {
+	Beta             float64
+	FreeEnergy       float64 // -ln(Z) / Beta
+	Energy           float64 // <E>
+	SpecificHeat     float64 // Beta^2 (<E^2> - <E>^2)
+	MetallicFraction float64 // <Na> / (Lx * Ly)
+}
+
+// Create a new (input-validated) WangLandau with the given parameters.  The
+// random number generator is seeded with TimeSeed(); use SetSeed to choose
+// the seed.
+func NewWangLandau(joint bool, flatness, lnfFinal float64, checkInterval, maxSweeps int) (*WangLandau, error) {
+	wl := new(WangLandau)
+	wl.joint = joint
+	wl.flatness = flatness
+	wl.lnfFinal = lnfFinal
+	wl.checkInterval = checkInterval
+	wl.maxSweeps = maxSweeps
+	wl.SetSeed(TimeSeed())
+	if !wl.validate() {
+		return nil, fmt.Errorf(WangLandauValidateError)
+	}
+	return wl, nil
+}
+
+// Do the fields of wl have acceptable values?
+func (wl *WangLandau) validate() bool {
+	return wl.flatness > 0 && wl.flatness < 1 && wl.lnfFinal > 0 && wl.lnfFinal < 1 && wl.checkInterval > 0 && wl.maxSweeps > 0
+}
+
+// Restart the random number generator of wl from seed.
+func (wl *WangLandau) SetSeed(seed int64) {
+	wl.seed = seed
+	wl.rng = NewRandom(seed)
+}
+
+// Return the seed last given to wl.
+func (wl *WangLandau) Seed() int64 {
+	return wl.seed
+}
+
+// Key identifying a macrostate.
+type wangLandauKey struct {
+	active, dimers int
+	energy         int64 // energy in units of wangLandauEnergyResolution
+}
+
+// Accumulated data for one macrostate.
+type wangLandauState struct {
+	lnG       float64
+	histogram int
+	energy    float64
+	// number of visits and sum of Na over visits
+	visits, activeSum float64
+}
+
+// Estimate the density of states of the atomic model on a grid with
+// dimensions (Lx, Ly), using the Delta and V of e.
+func (wl *WangLandau) Sample(e *Energetics, Lx, Ly int) (*DensityOfStates, error) {
+	if Lx <= 0 || Ly <= 0 {
+		return nil, fmt.Errorf("invalid grid dimensions")
+	}
+	grid := RandomFractionGrid(wl.rng, Lx, Ly, 0.5)
+	N := Lx * Ly
+	active, dimers := grid.ActiveSiteCount(), grid.DimerCount()
+	states := make(map[wangLandauKey]*wangLandauState)
+	lookup := func(active, dimers int) *wangLandauState {
+		energy := e.Delta()*float64(active) - e.V()*float64(dimers)
+		key := wangLandauKey{-1, -1, int64(math.Floor(energy/wangLandauEnergyResolution + 0.5))}
+		if wl.joint {
+			key = wangLandauKey{active, dimers, 0}
+		}
+		state, ok := states[key]
+		if !ok {
+			state = &wangLandauState{energy: energy}
+			states[key] = state
+		}
+		return state
+	}
+	current := lookup(active, dimers)
+	lnf := 1.0
+	dos := new(DensityOfStates)
+	dos.Sites = N
+	for lnf >= wl.lnfFinal {
+		if dos.Sweeps >= wl.maxSweeps {
+			return nil, fmt.Errorf(WangLandauSweepError, wl.maxSweeps)
+		}
+		for step := 0; step < wl.checkInterval*N; step++ {
+			p := RandomPoint(wl.rng, grid)
+			newActive, newDimers := active+1, dimers+grid.DimerChange(p)
+			if grid.Get(p) {
+				newActive = active - 1
+			}
+			proposed := lookup(newActive, newDimers)
+			if math.Log(RandomFloat(wl.rng)) < current.lnG-proposed.lnG {
+				grid.Toggle(p)
+				active, dimers, current = newActive, newDimers, proposed
+			}
+			current.lnG += lnf
+			current.histogram++
+			current.visits++
+			current.activeSum += float64(active)
+		}
+		dos.Sweeps += wl.checkInterval
+		if wl.flat(states) {
+			lnf /= 2
+			dos.Stages++
+			for _, state := range states {
+				state.histogram = 0
+			}
+		}
+	}
+	// normalize: sum g = 2^N
+	lnGs := []float64{}
+	for _, state := range states {
+		lnGs = append(lnGs, state.lnG)
+	}
+	shift := float64(N)*math.Ln2 - logSumExp(lnGs)
+	for key, state := range states {
+		level := DOSLevel{key.active, key.dimers, state.energy, state.lnG + shift, state.activeSum / state.visits}
+		dos.Levels = append(dos.Levels, level)
+	}
+	sort.Sort(dosLevelSorter(dos.Levels))
+	return dos, nil
+}
+
+// Is the visit histogram of the discovered states flat?
+func (wl *WangLandau) flat(states map[wangLandauKey]*wangLandauState) bool {
+	min, sum := math.Inf(1), 0.0
+	for _, state := range states {
+		h := float64(state.histogram)
+		min = math.Min(min, h)
+		sum += h
+	}
+	mean := sum / float64(len(states))
+	return mean > 0 && min >= wl.flatness*mean
+}
+
+// Return ln(sum_i exp(xs[i])) without overflow.
+func logSumExp(xs []float64) float64 {
+	max := math.Inf(-1)
+	for _, x := range xs {
+		max = math.Max(max, x)
+	}
+	sum := 0.0
+	for _, x := range xs {
+		sum += math.Exp(x - max)
+	}
+	return max + math.Log(sum)
+}
+
+// Return the thermodynamic averages at inverse temperature beta.
+func (dos *DensityOfStates) Thermodynamics(beta float64) Thermodynamics {
+	// Boltzmann weights relative to the largest one
+	logWeights := make([]float64, len(dos.Levels))
+	for i, level := range dos.Levels {
+		logWeights[i] = level.LnG - beta*level.Energy
+	}
+	lnZ := logSumExp(logWeights)
+	energy, energy2, active := 0.0, 0.0, 0.0
+	for i, level := range dos.Levels {
+		p := math.Exp(logWeights[i] - lnZ)
+		energy += p * level.Energy
+		energy2 += p * level.Energy * level.Energy
+		active += p * level.MeanActive
+	}
+	return Thermodynamics{beta, -lnZ / beta, energy, beta * beta * (energy2 - energy*energy), active / float64(dos.Sites)}
+}
+
+// Sorts levels by energy, then by active count and dimer count.
+type dosLevelSorter []DOSLevel
+
+func (s dosLevelSorter) Len() int {
+	return len(s)
+}
+
+func (s dosLevelSorter) Less(i, j int) bool {
+	if s[i].Energy != s[j].Energy {
+		return s[i].Energy < s[j].Energy
+	}
+	if s[i].Active != s[j].Active {
+		return s[i].Active < s[j].Active
+	}
+	return s[i].Dimers < s[j].Dimers
+}
+
+func (s dosLevelSorter) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
@@ -0,0 +1,88 @@
+package vo2percolation
+
+import (
+	"math"
+	"testing"
+)
+
+// Compare the Wang-Landau density of states of a small grid with exact
+// enumeration of all 2^8 configurations.
+func TestWangLandauExactDOS(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	Lx, Ly := 4, 2
+	// exact counts of each (Na, Nd) macrostate
+	type macrostate struct{ active, dimers int }
+	exact := make(map[macrostate]float64)
+	grid := NewGridWithDims(Lx, Ly)
+	for {
+		exact[macrostate{grid.ActiveSiteCount(), grid.DimerCount()}]++
+		if grid.NextGrid() {
+			break
+		}
+	}
+	wl, err := NewWangLandau(true, 0.9, 1e-7, 100, 10000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wl.SetSeed(3)
+	dos, err := wl.Sample(e, Lx, Ly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dos.Levels) != len(exact) {
+		t.Fatalf("found %d macrostates; expected %d", len(dos.Levels), len(exact))
+	}
+	for _, level := range dos.Levels {
+		expected := math.Log(exact[macrostate{level.Active, level.Dimers}])
+		if math.Abs(level.LnG-expected) > 0.1 {
+			t.Fatalf("ln g(%d, %d) = %v; expected %v", level.Active, level.Dimers, level.LnG, expected)
+		}
+	}
+	// thermodynamics against the exact density of states
+	beta := 1.0
+	Z, active := 0.0, 0.0
+	for m, count := range exact {
+		energy := e.Delta()*float64(m.active) - e.V()*float64(m.dimers)
+		w := count * math.Exp(-beta*energy)
+		Z += w
+		active += w * float64(m.active)
+	}
+	thermo := dos.Thermodynamics(beta)
+	if math.Abs(thermo.FreeEnergy+math.Log(Z)/beta) > 0.05 {
+		t.Fatalf("free energy %v; expected %v", thermo.FreeEnergy, -math.Log(Z)/beta)
+	}
+	expectedFraction := active / Z / float64(Lx*Ly)
+	if math.Abs(thermo.MetallicFraction-expectedFraction) > 0.02 {
+		t.Fatalf("metallic fraction %v; expected %v", thermo.MetallicFraction, expectedFraction)
+	}
+}
+
+// Resolving only by energy merges macrostates with equal energy.
+func TestWangLandauEnergyLevels(t *testing.T) {
+	env, err := EnvironmentFromFile("default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnergetics(*env)
+	wl, err := NewWangLandau(false, 0.8, 1e-3, 10, 1000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wl.SetSeed(5)
+	dos, err := wl.Sample(e, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, level := range dos.Levels {
+		if level.Active != -1 || level.Dimers != -1 {
+			t.Fatalf("energy-only level has active and dimer counts")
+		}
+		if i > 0 && level.Energy-dos.Levels[i-1].Energy < 1e-9 {
+			t.Fatalf("repeated energy level %v", level.Energy)
+		}
+	}
+}